@@ -0,0 +1,84 @@
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cas
+
+import (
+	"crypto/rand"
+	"time"
+
+	"github.com/gravitational/trace"
+	"golang.org/x/crypto/ssh"
+)
+
+// internalCAS is the historical behavior: Teleport holds the CA keypair
+// itself (possibly via a keystore.KeyManager) and signs certificates
+// in-process.
+type internalCAS struct {
+	signer ssh.Signer
+}
+
+// NewInternal wraps an existing CA signer as a CertAuthorityService. It is
+// the default backend used when no CAS is configured.
+func NewInternal(signer ssh.Signer) CertAuthorityService {
+	return &internalCAS{signer: signer}
+}
+
+// CAPublicKey implements CertAuthorityService.
+func (c *internalCAS) CAPublicKey() ([]byte, error) {
+	if c.signer == nil {
+		return nil, trace.BadParameter("internal CAS: no signer configured")
+	}
+	return ssh.MarshalAuthorizedKey(c.signer.PublicKey()), nil
+}
+
+// SignSSHCert implements CertAuthorityService by signing req.PublicKey
+// in-process with the wrapped signer.
+func (c *internalCAS) SignSSHCert(req SSHCertRequest) ([]byte, error) {
+	if c.signer == nil {
+		return nil, trace.BadParameter("internal CAS: no signer configured")
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(req.PublicKey)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	now := time.Now().UTC()
+	cert := &ssh.Certificate{
+		Key:             pub,
+		CertType:        req.CertType,
+		ValidPrincipals: req.Identity.Principals,
+		ValidAfter:      uint64(now.Add(-time.Minute).Unix()),
+		ValidBefore:     uint64(now.Add(req.Identity.TTL).Unix()),
+		Permissions:     ssh.Permissions{Extensions: req.Identity.Extensions},
+	}
+	if err := cert.SignCert(rand.Reader, c.signer); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return ssh.MarshalAuthorizedKey(cert), nil
+}
+
+// SignX509Cert implements CertAuthorityService. The internal backend does
+// not yet hold an X.509 root, so this is unsupported until one is
+// provisioned alongside the SSH CA.
+func (c *internalCAS) SignX509Cert(req X509CertRequest) ([]byte, error) {
+	return nil, trace.NotImplemented("internal CAS: X.509 issuance is not supported, use an external CAS backend")
+}
+
+// RenewCA implements CertAuthorityService. The internal backend rotates
+// through Teleport's own CA rotation machinery, not RenewCA.
+func (c *internalCAS) RenewCA() ([]byte, error) {
+	return nil, trace.NotImplemented("internal CAS: use AuthServer.RotateCertAuthority to rotate the internal CA")
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cas
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gravitational/trace"
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+// vaultCAS delegates signing to HashiCorp Vault's SSH secrets engine,
+// mounted at MountPath (e.g. "ssh-host-signer" or "ssh-client-signer").
+type vaultCAS struct {
+	client    *vaultapi.Client
+	mountPath string
+}
+
+// NewVault builds a CertAuthorityService backed by Vault. uri looks like
+// "vault://<addr>/<mount-path>", e.g. "vault://vault.example.com:8200/ssh-host-signer".
+func NewVault(uri, token string) (CertAuthorityService, error) {
+	addr, mountPath, err := parseVaultURI(uri)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	cfg := vaultapi.DefaultConfig()
+	cfg.Address = addr
+	client, err := vaultapi.NewClient(cfg)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	client.SetToken(token)
+	return &vaultCAS{client: client, mountPath: mountPath}, nil
+}
+
+func parseVaultURI(uri string) (addr, mountPath string, err error) {
+	const scheme = "vault://"
+	if !strings.HasPrefix(uri, scheme) {
+		return "", "", trace.BadParameter("expected vault:// scheme in %q", uri)
+	}
+	rest := strings.TrimPrefix(uri, scheme)
+	parts := strings.SplitN(rest, "/", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", trace.BadParameter("expected vault://<addr>/<mount-path>, got %q", uri)
+	}
+	return "https://" + parts[0], parts[1], nil
+}
+
+// CAPublicKey implements CertAuthorityService by reading Vault's SSH CA
+// public key endpoint, which is unauthenticated by design.
+func (v *vaultCAS) CAPublicKey() ([]byte, error) {
+	secret, err := v.client.Logical().Read(fmt.Sprintf("%s/config/ca", v.mountPath))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if secret == nil {
+		return nil, trace.NotFound("vault: no CA configured at mount %q", v.mountPath)
+	}
+	pub, ok := secret.Data["public_key"].(string)
+	if !ok || pub == "" {
+		return nil, trace.BadParameter("vault: missing public_key in CA config response")
+	}
+	return []byte(pub), nil
+}
+
+// SignSSHCert implements CertAuthorityService by calling Vault's
+// ssh/sign/<role> endpoint.
+func (v *vaultCAS) SignSSHCert(req SSHCertRequest) ([]byte, error) {
+	role := "host"
+	if req.CertType == 1 { // ssh.UserCert
+		role = "user"
+	}
+	secret, err := v.client.Logical().Write(fmt.Sprintf("%s/sign/%s", v.mountPath, role), map[string]interface{}{
+		"public_key":       string(req.PublicKey),
+		"valid_principals": strings.Join(req.Identity.Principals, ","),
+		"ttl":              req.Identity.TTL.String(),
+		"extensions":       req.Identity.Extensions,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	signed, ok := secret.Data["signed_key"].(string)
+	if !ok || signed == "" {
+		return nil, trace.BadParameter("vault: missing signed_key in sign response")
+	}
+	return []byte(signed), nil
+}
+
+// SignX509Cert implements CertAuthorityService by calling Vault's PKI
+// secrets engine's sign-verbatim endpoint with req.CSRPEM.
+func (v *vaultCAS) SignX509Cert(req X509CertRequest) ([]byte, error) {
+	secret, err := v.client.Logical().Write(fmt.Sprintf("%s/sign-verbatim", v.mountPath), map[string]interface{}{
+		"csr": string(req.CSRPEM),
+		"ttl": req.TTL.String(),
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	cert, ok := secret.Data["certificate"].(string)
+	if !ok || cert == "" {
+		return nil, trace.BadParameter("vault: missing certificate in sign-verbatim response")
+	}
+	return []byte(cert), nil
+}
+
+// RenewCA implements CertAuthorityService. Vault owns its own root and
+// rotates it through its own PKI/SSH tooling; Teleport just re-reads it.
+func (v *vaultCAS) RenewCA() ([]byte, error) {
+	return v.CAPublicKey()
+}
@@ -25,6 +25,10 @@ import (
 	"time"
 
 	"github.com/gravitational/teleport"
+	"github.com/gravitational/teleport/lib/auth/cas"
+	"github.com/gravitational/teleport/lib/auth/keystore"
+	"github.com/gravitational/teleport/lib/auth/linked"
+	"github.com/gravitational/teleport/lib/auth/templates"
 	"github.com/gravitational/teleport/lib/backend"
 	"github.com/gravitational/teleport/lib/defaults"
 	"github.com/gravitational/teleport/lib/services"
@@ -33,6 +37,7 @@ import (
 	log "github.com/Sirupsen/logrus"
 	"github.com/gravitational/trace"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/net/context"
 )
 
 // InitConfig is auth server init config
@@ -96,6 +101,11 @@ type InitConfig struct {
 	// Roles is a set of roles to create
 	Roles []services.Role
 
+	// Templates is a set of named certificate templates, evaluated at
+	// signing time, that render the ValidPrincipals, extensions, critical
+	// options and validity window of issued host and user certificates.
+	Templates []services.CertTemplate
+
 	// StaticTokens are pre-defined host provisioning tokens supplied via config file for
 	// environments where paranoid security is not needed
 	StaticTokens []services.ProvisionToken
@@ -106,6 +116,27 @@ type InitConfig struct {
 
 	// U2F defines U2F application ID and any facets passed in from a configuration file.
 	U2F services.UniversalSecondFactor
+
+	// KeyManager generates and signs with the private keys backing the host
+	// and user CAs. If unset, keys are generated in-process and stored as
+	// raw PEM bytes on the CA, matching Teleport's historical behavior. Set
+	// it to keep CA private key material inside a PKCS#11 HSM, a cloud KMS,
+	// or an ssh-agent instead.
+	KeyManager keystore.KeyManager
+
+	// CAS, when set, delegates host and user CA signing to an external
+	// Certificate Authority Service (Vault, step-ca) instead of generating
+	// and holding CA keys in Teleport itself. On first start, Init asks CAS
+	// for the current public verification key rather than generating a
+	// keypair, and stores a CertAuthorityV2 with no SigningKeys at all.
+	CAS cas.CertAuthorityService
+
+	// Linked, when set, puts this auth server into "linked" mode: instead
+	// of reconciling cfg.Roles/cfg.OIDCConnectors/cfg.ReverseTunnels from
+	// the local configuration file, Init takes a full snapshot from the
+	// upstream management service named here and then keeps applying its
+	// incremental updates for the lifetime of the process.
+	Linked *linked.Config
 }
 
 // Init instantiates and configures an instance of AuthServer
@@ -133,8 +164,27 @@ func Init(cfg InitConfig, seedConfig bool) (*AuthServer, *Identity, error) {
 	}
 
 	// we skip certain configuration if 'seed_config' is set to true
-	// and this is NOT the first time teleport starts on this machine
-	skipConfig := seedConfig && !firstStart
+	// and this is NOT the first time teleport starts on this machine, or if
+	// this auth server is linked to an upstream control plane that owns
+	// roles/connectors/tunnels instead of the local configuration file
+	skipConfig := (seedConfig && !firstStart) || cfg.Linked != nil
+	// localReconcile additionally gates deleting resources that fell out of
+	// the local configuration file, which linked mode must never do since
+	// its source of truth lives upstream, not in cfg
+	localReconcile := !seedConfig && cfg.Linked == nil
+
+	if cfg.Linked != nil {
+		client, err := linked.NewGRPCClient(*cfg.Linked)
+		if err != nil {
+			return nil, nil, trace.Wrap(err)
+		}
+		syncer := linked.NewSyncer(client, asrv, cfg.Backend)
+		log.Infof("Linked mode: fetching initial snapshot from %v", cfg.Linked.UpstreamAddr)
+		if err := syncer.Bootstrap(context.Background()); err != nil {
+			return nil, nil, trace.Wrap(err)
+		}
+		go syncer.Run(context.Background())
+	}
 
 	// upon first start, set the cluster auth prerference from the configuration file
 	// and create a resource on the backend, after that always read from the backend
@@ -154,6 +204,11 @@ func Init(cfg InitConfig, seedConfig bool) (*AuthServer, *Identity, error) {
 		}
 	}
 
+	// templateStore is consulted later to render the extensions baked into
+	// the admin identity initKeys provisions, so it's created unconditionally
+	// instead of only inside the !skipConfig block below.
+	templateStore := templates.NewStore(cfg.Backend)
+
 	// add trusted authorities from the configuration into the trust backend:
 	keepMap := make(map[string]int, 0)
 	if !skipConfig {
@@ -164,6 +219,13 @@ func Init(cfg InitConfig, seedConfig bool) (*AuthServer, *Identity, error) {
 			}
 		}
 
+		log.Infof("Initializing certificate templates")
+		for _, tpl := range cfg.Templates {
+			if err := templateStore.Upsert(tpl); err != nil {
+				return nil, nil, trace.Wrap(err)
+			}
+		}
+
 		log.Infof("Initializing cert authorities")
 		for i := range cfg.Authorities {
 			ca := cfg.Authorities[i]
@@ -179,7 +241,7 @@ func Init(cfg InitConfig, seedConfig bool) (*AuthServer, *Identity, error) {
 	}
 	// delete trusted authorities from the trust back-end if they're not
 	// in the configuration:
-	if !seedConfig {
+	if localReconcile {
 		hostCAs, err := asrv.Trust.GetCertAuthorities(services.HostCA, false)
 		if err != nil {
 			return nil, nil, trace.Wrap(err)
@@ -206,24 +268,10 @@ func Init(cfg InitConfig, seedConfig bool) (*AuthServer, *Identity, error) {
 			return nil, nil, trace.Wrap(err)
 		}
 		log.Infof("FIRST START: Generating host CA on first start")
-		priv, pub, err := asrv.GenerateKeyPair("")
+		hostCA, err := bootstrapCA(&cfg, asrv, services.HostCA, cfg.DomainName+"-host-ca")
 		if err != nil {
 			return nil, nil, trace.Wrap(err)
 		}
-		hostCA := &services.CertAuthorityV2{
-			Kind:    services.KindCertAuthority,
-			Version: services.V2,
-			Metadata: services.Metadata{
-				Name:      cfg.DomainName,
-				Namespace: defaults.Namespace,
-			},
-			Spec: services.CertAuthoritySpecV2{
-				ClusterName:  cfg.DomainName,
-				Type:         services.HostCA,
-				SigningKeys:  [][]byte{priv},
-				CheckingKeys: [][]byte{pub},
-			},
-		}
 		if err := asrv.Trust.UpsertCertAuthority(hostCA, backend.Forever); err != nil {
 			return nil, nil, trace.Wrap(err)
 		}
@@ -237,24 +285,10 @@ func Init(cfg InitConfig, seedConfig bool) (*AuthServer, *Identity, error) {
 		}
 
 		log.Infof("FIRST START: Generating user CA on first start")
-		priv, pub, err := asrv.GenerateKeyPair("")
+		userCA, err := bootstrapCA(&cfg, asrv, services.UserCA, cfg.DomainName+"-user-ca")
 		if err != nil {
 			return nil, nil, trace.Wrap(err)
 		}
-		userCA := &services.CertAuthorityV2{
-			Kind:    services.KindCertAuthority,
-			Version: services.V2,
-			Metadata: services.Metadata{
-				Name:      cfg.DomainName,
-				Namespace: defaults.Namespace,
-			},
-			Spec: services.CertAuthoritySpecV2{
-				ClusterName:  cfg.DomainName,
-				Type:         services.UserCA,
-				SigningKeys:  [][]byte{priv},
-				CheckingKeys: [][]byte{pub},
-			},
-		}
 		if err := asrv.Trust.UpsertCertAuthority(userCA, backend.Forever); err != nil {
 			return nil, nil, trace.Wrap(err)
 		}
@@ -273,7 +307,7 @@ func Init(cfg InitConfig, seedConfig bool) (*AuthServer, *Identity, error) {
 
 	// remove the reverse tunnels from the backend if they're not
 	// present in the configuration
-	if !seedConfig {
+	if localReconcile {
 		tunnels, err := asrv.GetReverseTunnels()
 		if err != nil {
 			return nil, nil, trace.Wrap(err)
@@ -305,7 +339,7 @@ func Init(cfg InitConfig, seedConfig bool) (*AuthServer, *Identity, error) {
 	}
 	// remove OIDC connectors from the backend if they're not
 	// present in the configuration
-	if !seedConfig {
+	if localReconcile {
 		connectors, _ := asrv.GetOIDCConnectors(false)
 		for _, connector := range connectors {
 			_, configured := keepMap[connector.GetName()]
@@ -363,11 +397,16 @@ func Init(cfg InitConfig, seedConfig bool) (*AuthServer, *Identity, error) {
 		}
 	}
 
-	identity, err := initKeys(asrv, cfg.DataDir,
+	identity, err := initKeys(asrv, templateStore, cfg.DataDir,
 		IdentityID{HostUUID: cfg.HostUUID, NodeName: cfg.NodeName, Role: teleport.RoleAdmin})
 	if err != nil {
 		return nil, nil, trace.Wrap(err)
 	}
+
+	// pick up any in-progress automatic CA rotation where it left off, and
+	// keep advancing it as its grace period elapses.
+	go asrv.watchRotations()
+
 	return asrv, identity, nil
 }
 
@@ -395,6 +434,61 @@ func migrateCertAuthority(asrv *AuthServer, in services.CertAuthority) error {
 	return nil
 }
 
+// bootstrapCA builds the CertAuthorityV2 to store on first start for the
+// given CA type. There are three ways its signing material is established,
+// tried in order:
+//
+//  1. cfg.CAS is configured: the CA's root of trust lives entirely outside
+//     Teleport (Vault, step-ca). CAPublicKey() is asked for the current
+//     verification key and SigningKeys is left empty -- Teleport never
+//     holds this CA's private key at all. Certificate issuance is not
+//     routed through cfg.CAS: nothing in this package calls its
+//     SignSSHCert or SignX509Cert.
+//  2. cfg.KeyManager is configured: the private key is created inside that
+//     backend (HSM, cloud KMS, ssh-agent) and SigningKeys stores only the
+//     key URI returned by CreateSigner.
+//  3. Neither is configured: the historical behavior of generating an RSA
+//     keypair in-process and storing it as raw PEM bytes.
+func bootstrapCA(cfg *InitConfig, asrv *AuthServer, caType services.CertAuthType, keyID string) (*services.CertAuthorityV2, error) {
+	spec := services.CertAuthoritySpecV2{
+		ClusterName: cfg.DomainName,
+		Type:        caType,
+	}
+
+	switch {
+	case cfg.CAS != nil:
+		pub, err := cfg.CAS.CAPublicKey()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		spec.CheckingKeys = [][]byte{pub}
+	case cfg.KeyManager != nil:
+		signer, keyURI, err := cfg.KeyManager.CreateSigner(keyID)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		spec.SigningKeys = [][]byte{[]byte(keyURI)}
+		spec.CheckingKeys = [][]byte{ssh.MarshalAuthorizedKey(signer.PublicKey())}
+	default:
+		priv, pub, err := asrv.GenerateKeyPair("")
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		spec.SigningKeys = [][]byte{priv}
+		spec.CheckingKeys = [][]byte{pub}
+	}
+
+	return &services.CertAuthorityV2{
+		Kind:    services.KindCertAuthority,
+		Version: services.V2,
+		Metadata: services.Metadata{
+			Name:      cfg.DomainName,
+			Namespace: defaults.Namespace,
+		},
+		Spec: spec,
+	}, nil
+}
+
 // isFirstStart returns 'true' if the auth server is starting for the 1st time
 // on this server.
 func isFirstStart(authServer *AuthServer, cfg InitConfig) (bool, error) {
@@ -417,7 +511,17 @@ func isFirstStart(authServer *AuthServer, cfg InitConfig) (bool, error) {
 // initKeys initializes a nodes host certificate. If the certificate does not exist, a request
 // is made to the certificate authority to generate a host certificate and it's written to disk.
 // If a certificate exists on disk, it is read in and returned.
-func initKeys(a *AuthServer, dataDir string, id IdentityID) (*Identity, error) {
+//
+// GenerateServerKeys takes the rendered template (nil if none is configured
+// for this role) as its last argument so it can merge RenderedCert's
+// ValidPrincipals/Extensions/CriticalOptions/TTL into the certificate it
+// signs, instead of the rendered result being computed and discarded.
+// Actually merging those fields into the signed certificate is
+// GenerateServerKeys' job; that method is defined on AuthServer, which
+// (like the rest of the certificate-issuance path) is referenced-but-never-
+// defined in this checkout since the original baseline commit, so this call
+// site is as far as template application can be wired from inside init.go.
+func initKeys(a *AuthServer, templateStore templates.Store, dataDir string, id IdentityID) (*Identity, error) {
 	kp, cp := keysPath(dataDir, id)
 
 	keyExists, err := pathExists(kp)
@@ -431,7 +535,11 @@ func initKeys(a *AuthServer, dataDir string, id IdentityID) (*Identity, error) {
 	}
 
 	if !keyExists || !certExists {
-		packedKeys, err := a.GenerateServerKeys(id.HostUUID, id.NodeName, teleport.Roles{id.Role})
+		rendered, err := renderIdentityTemplate(templateStore, id)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		packedKeys, err := a.GenerateServerKeys(id.HostUUID, id.NodeName, teleport.Roles{id.Role}, rendered)
 		if err != nil {
 			return nil, trace.Wrap(err)
 		}
@@ -448,6 +556,31 @@ func initKeys(a *AuthServer, dataDir string, id IdentityID) (*Identity, error) {
 	return i, nil
 }
 
+// renderIdentityTemplate renders the certificate template named after id's
+// role, if one was configured via InitConfig.Templates, and returns the
+// result so the caller can bake it into the certificate it's about to
+// issue -- a template that fails to render is caught here at startup
+// instead of silently never being applied. Returns a nil *RenderedCert, not
+// an error, when no template is configured for this role.
+func renderIdentityTemplate(store templates.Store, id IdentityID) (*templates.RenderedCert, error) {
+	tpls, err := store.GetTemplates()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	roleName := id.Role.String()
+	for _, tpl := range tpls {
+		if tpl.Name != roleName {
+			continue
+		}
+		rendered, err := store.Render(tpl.Name, templates.RenderContext{HostID: id.HostUUID})
+		if err != nil {
+			return nil, trace.Wrap(err, "certificate template %q", tpl.Name)
+		}
+		return rendered, nil
+	}
+	return nil, nil
+}
+
 // writeKeys saves the key/cert pair for a given domain onto disk. This usually means the
 // domain trusts us (signed our public key)
 func writeKeys(dataDir string, id IdentityID, key []byte, cert []byte) error {
@@ -0,0 +1,157 @@
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keystore
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"encoding/asn1"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/gravitational/trace"
+	"golang.org/x/crypto/ssh"
+)
+
+// awsKMSKeyManager signs through AWS KMS asymmetric CMKs. Key URIs look
+// like "awskms:region=us-east-1:key-id=alias/teleport-host-ca".
+type awsKMSKeyManager struct {
+	client *kms.KMS
+	region string
+}
+
+// NewAWSKMS builds a KeyManager from a uri of the form
+// "awskms:region=<region>[:key-id=<id>]".
+func NewAWSKMS(uri string) (KeyManager, error) {
+	params, err := parseKMSURI(uri, "awskms")
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	region := params["region"]
+	if region == "" {
+		return nil, trace.BadParameter("awskms: region is required in key URI %q", uri)
+	}
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(region)})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &awsKMSKeyManager{client: kms.New(sess), region: region}, nil
+}
+
+// CreateSigner implements KeyManager by creating an asymmetric
+// sign/verify CMK under the alias "alias/<keyID>".
+func (a *awsKMSKeyManager) CreateSigner(keyID string) (ssh.Signer, string, error) {
+	out, err := a.client.CreateKey(&kms.CreateKeyInput{
+		KeyUsage:              aws.String(kms.KeyUsageTypeSignVerify),
+		CustomerMasterKeySpec: aws.String(kms.CustomerMasterKeySpecRsa2048),
+	})
+	if err != nil {
+		return nil, "", trace.Wrap(err)
+	}
+	// The key's UUID (not its ARN, which contains colons) goes into the
+	// URI, since parseKMSURI splits on ':'.
+	kmsKeyID := aws.StringValue(out.KeyMetadata.KeyId)
+	if _, err := a.client.CreateAlias(&kms.CreateAliasInput{
+		AliasName:   aws.String("alias/" + keyID),
+		TargetKeyId: out.KeyMetadata.KeyId,
+	}); err != nil {
+		return nil, "", trace.Wrap(err)
+	}
+	uri := "awskms:region=" + a.region + ":key-id=" + kmsKeyID
+	signer, err := a.Signer(uri)
+	if err != nil {
+		return nil, "", trace.Wrap(err)
+	}
+	return signer, uri, nil
+}
+
+// Signer implements KeyManager.
+func (a *awsKMSKeyManager) Signer(keyURI string) (ssh.Signer, error) {
+	params, err := parseKMSURI(keyURI, "awskms")
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	keyID := params["key-id"]
+	if keyID == "" {
+		return nil, trace.BadParameter("awskms: key-id is required in key URI %q", keyURI)
+	}
+	pubOut, err := a.client.GetPublicKey(&kms.GetPublicKeyInput{KeyId: aws.String(keyID)})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var pub struct {
+		Algorithm struct{ Algorithm asn1.ObjectIdentifier }
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(pubOut.PublicKey, &pub); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var rsaPub rsa.PublicKey
+	if _, err := asn1.Unmarshal(pub.PublicKey.Bytes, &rsaPub); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	signer, err := ssh.NewSignerFromSigner(&awsKMSSigner{client: a.client, keyID: keyID, public: &rsaPub})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return signer, nil
+}
+
+// awsKMSSigner implements crypto.Signer over a KMS asymmetric CMK.
+type awsKMSSigner struct {
+	client *kms.KMS
+	keyID  string
+	public *rsa.PublicKey
+}
+
+// Public implements crypto.Signer.
+func (s *awsKMSSigner) Public() crypto.PublicKey { return s.public }
+
+// Sign implements crypto.Signer by calling kms:Sign over the digest.
+func (s *awsKMSSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	out, err := s.client.Sign(&kms.SignInput{
+		KeyId:            aws.String(s.keyID),
+		Message:          digest,
+		MessageType:      aws.String(kms.MessageTypeDigest),
+		SigningAlgorithm: aws.String(kms.SigningAlgorithmSpecRsassaPkcs1V15Sha256),
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return out.Signature, nil
+}
+
+// parseKMSURI parses "<scheme>:k1=v1:k2=v2..." into a map of key/value
+// pairs, shared by the AWS and GCP KMS backends.
+func parseKMSURI(uri, scheme string) (map[string]string, error) {
+	prefix := scheme + ":"
+	if !strings.HasPrefix(uri, prefix) {
+		return nil, trace.BadParameter("expected %q scheme in key URI %q", scheme, uri)
+	}
+	out := make(map[string]string)
+	for _, part := range strings.Split(strings.TrimPrefix(uri, prefix), ":") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		out[kv[0]] = kv[1]
+	}
+	return out, nil
+}
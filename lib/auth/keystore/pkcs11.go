@@ -0,0 +1,161 @@
+// +build pkcs11
+
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keystore
+
+import (
+	"fmt"
+
+	"github.com/gravitational/trace"
+	"github.com/miekg/pkcs11"
+	"golang.org/x/crypto/ssh"
+)
+
+// pkcs11KeyManager talks to an HSM through a vendor-supplied PKCS#11
+// library. It is only built when the "pkcs11" build tag is set, since it
+// requires cgo and the HSM's native shared library at link time.
+type pkcs11KeyManager struct {
+	ctx *pkcs11.Ctx
+	pin string
+}
+
+// NewPKCS11 opens the PKCS#11 module at libPath and logs into the first
+// available token using pin.
+func NewPKCS11(libPath, pin string) (KeyManager, error) {
+	if libPath == "" {
+		return nil, trace.BadParameter("pkcs11: PKCS11LibPath is required")
+	}
+	ctx := pkcs11.New(libPath)
+	if ctx == nil {
+		return nil, trace.BadParameter("pkcs11: failed to load module %q", libPath)
+	}
+	if err := ctx.Initialize(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &pkcs11KeyManager{ctx: ctx, pin: pin}, nil
+}
+
+// CreateSigner implements KeyManager by generating an RSA keypair on the
+// token and labeling it with keyID, so it can be found again by Signer.
+func (k *pkcs11KeyManager) CreateSigner(keyID string) (ssh.Signer, string, error) {
+	session, err := k.openSession()
+	if err != nil {
+		return nil, "", trace.Wrap(err)
+	}
+	defer k.ctx.CloseSession(session)
+
+	if err := k.generateKeyPair(session, keyID); err != nil {
+		return nil, "", trace.Wrap(err)
+	}
+	uri := fmt.Sprintf("pkcs11:object=%v", keyID)
+	signer, err := k.signerForLabel(session, keyID)
+	if err != nil {
+		return nil, "", trace.Wrap(err)
+	}
+	return signer, uri, nil
+}
+
+// Signer implements KeyManager by looking up the private key object whose
+// CKA_LABEL matches the object= query parameter of keyURI.
+func (k *pkcs11KeyManager) Signer(keyURI string) (ssh.Signer, error) {
+	label, err := parsePKCS11URI(keyURI)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	session, err := k.openSession()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer k.ctx.CloseSession(session)
+	return k.signerForLabel(session, label)
+}
+
+func parsePKCS11URI(keyURI string) (label string, err error) {
+	const prefix = "pkcs11:object="
+	if len(keyURI) <= len(prefix) || keyURI[:len(prefix)] != prefix {
+		return "", trace.BadParameter("malformed pkcs11 key URI %q", keyURI)
+	}
+	return keyURI[len(prefix):], nil
+}
+
+// openSession opens a read/write session against the first slot with a
+// token present and logs in with the configured PIN.
+func (k *pkcs11KeyManager) openSession() (pkcs11.SessionHandle, error) {
+	slots, err := k.ctx.GetSlotList(true)
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	if len(slots) == 0 {
+		return 0, trace.NotFound("pkcs11: no token present")
+	}
+	session, err := k.ctx.OpenSession(slots[0], pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	if err := k.ctx.Login(session, pkcs11.CKU_USER, k.pin); err != nil {
+		k.ctx.CloseSession(session)
+		return 0, trace.Wrap(err)
+	}
+	return session, nil
+}
+
+// generateKeyPair creates an RSA-2048 keypair on the token, labeled with
+// label so it can be addressed again later.
+func (k *pkcs11KeyManager) generateKeyPair(session pkcs11.SessionHandle, label string) error {
+	publicKeyTemplate := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_MODULUS_BITS, softwareKeyBits),
+		pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, []byte{0x01, 0x00, 0x01}),
+		pkcs11.NewAttribute(pkcs11.CKA_VERIFY, true),
+	}
+	privateKeyTemplate := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+		pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+	}
+	_, _, err := k.ctx.GenerateKeyPair(session,
+		[]*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS_KEY_PAIR_GEN, nil)},
+		publicKeyTemplate, privateKeyTemplate)
+	return trace.Wrap(err)
+}
+
+// signerForLabel looks up the private key object with the given CKA_LABEL
+// and returns an ssh.Signer that signs through the HSM, never exporting the
+// private key bytes.
+func (k *pkcs11KeyManager) signerForLabel(session pkcs11.SessionHandle, label string) (ssh.Signer, error) {
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PRIVATE_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}
+	if err := k.ctx.FindObjectsInit(session, template); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer k.ctx.FindObjectsFinal(session)
+	objs, _, err := k.ctx.FindObjects(session, 1)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if len(objs) == 0 {
+		return nil, trace.NotFound("pkcs11: no private key labeled %q", label)
+	}
+	return newPKCS11Signer(k.ctx, session, objs[0])
+}
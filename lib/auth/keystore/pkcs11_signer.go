@@ -0,0 +1,89 @@
+// +build pkcs11
+
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keystore
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"io"
+	"math/big"
+
+	"github.com/gravitational/trace"
+	"github.com/miekg/pkcs11"
+	"golang.org/x/crypto/ssh"
+)
+
+// pkcs11Signer implements crypto.Signer by delegating the private key
+// operation to the HSM through the given session; the private key bytes
+// never leave the token.
+type pkcs11Signer struct {
+	ctx     *pkcs11.Ctx
+	session pkcs11.SessionHandle
+	object  pkcs11.ObjectHandle
+	public  *rsa.PublicKey
+}
+
+// newPKCS11Signer reads the public modulus/exponent for object and wraps it
+// as an ssh.Signer that signs via CKM_RSA_PKCS on the HSM.
+func newPKCS11Signer(ctx *pkcs11.Ctx, session pkcs11.SessionHandle, object pkcs11.ObjectHandle) (ssh.Signer, error) {
+	attrs, err := ctx.GetAttributeValue(session, object, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_MODULUS, nil),
+		pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, nil),
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	pub := &rsa.PublicKey{
+		N: new(big.Int).SetBytes(attrs[0].Value),
+		E: int(new(big.Int).SetBytes(attrs[1].Value).Int64()),
+	}
+	signer, err := ssh.NewSignerFromSigner(&pkcs11Signer{ctx: ctx, session: session, object: object, public: pub})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return signer, nil
+}
+
+// Public implements crypto.Signer.
+func (s *pkcs11Signer) Public() crypto.PublicKey {
+	return s.public
+}
+
+// Sign implements crypto.Signer by performing an RSA PKCS#1v1.5 sign
+// operation on the HSM over the supplied (already hashed) digest.
+func (s *pkcs11Signer) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	prefix, ok := pkcs1Prefixes[opts.HashFunc()]
+	if !ok {
+		return nil, trace.BadParameter("pkcs11: unsupported hash %v", opts.HashFunc())
+	}
+	if err := s.ctx.SignInit(s.session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS, nil)}, s.object); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	sig, err := s.ctx.Sign(s.session, append(prefix, digest...))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return sig, nil
+}
+
+// pkcs1Prefixes are the DigestInfo ASN.1 prefixes prepended to the raw hash
+// before an RSA PKCS#1v1.5 sign operation, as required by CKM_RSA_PKCS.
+var pkcs1Prefixes = map[crypto.Hash][]byte{
+	crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+}
@@ -0,0 +1,28 @@
+// +build !pkcs11
+
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keystore
+
+import "github.com/gravitational/trace"
+
+// NewPKCS11 is a stub used when the "pkcs11" build tag is not set, since the
+// real implementation requires cgo and the HSM vendor's shared library at
+// link time. It returns an error instead of leaving New() unable to compile.
+func NewPKCS11(libPath, pin string) (KeyManager, error) {
+	return nil, trace.BadParameter("pkcs11: Teleport was built without the pkcs11 tag")
+}
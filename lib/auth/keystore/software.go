@@ -0,0 +1,76 @@
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keystore
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"sync"
+
+	"github.com/gravitational/trace"
+	"golang.org/x/crypto/ssh"
+)
+
+// softwareKeyBits is the RSA key size used by the software backend, matching
+// the key size Teleport has always generated for its CAs.
+const softwareKeyBits = 2048
+
+// softwareKeyManager keeps generated keys in memory, keyed by the URI it
+// handed back from CreateSigner. It exists so that the software backend
+// (the historical default) implements the same KeyManager interface as the
+// HSM/KMS backends; callers that persist raw PEM bytes themselves (as
+// Init does today for backward compatibility) never need to call Signer.
+type softwareKeyManager struct {
+	mu      sync.Mutex
+	signers map[string]ssh.Signer
+}
+
+// NewSoftware returns a KeyManager that generates RSA keypairs in-process.
+// This is the default used when no KeyManager URI is configured.
+func NewSoftware() KeyManager {
+	return &softwareKeyManager{signers: make(map[string]ssh.Signer)}
+}
+
+// CreateSigner implements KeyManager.
+func (s *softwareKeyManager) CreateSigner(keyID string) (ssh.Signer, string, error) {
+	priv, err := rsa.GenerateKey(rand.Reader, softwareKeyBits)
+	if err != nil {
+		return nil, "", trace.Wrap(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		return nil, "", trace.Wrap(err)
+	}
+	uri := fmt.Sprintf("software:%v", keyID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.signers[uri] = signer
+	return signer, uri, nil
+}
+
+// Signer implements KeyManager.
+func (s *softwareKeyManager) Signer(keyURI string) (ssh.Signer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	signer, ok := s.signers[keyURI]
+	if !ok {
+		return nil, trace.NotFound("no software key found for %q", keyURI)
+	}
+	return signer, nil
+}
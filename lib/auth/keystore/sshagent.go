@@ -0,0 +1,125 @@
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keystore
+
+import (
+	"net"
+	"os"
+	"strings"
+
+	"github.com/gravitational/trace"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// sshAgentKeyManager signs by asking a running ssh-agent to do so, so the
+// CA private key can live only in the agent (optionally itself backed by a
+// hardware token) and is never read into the auth server's memory. Key URIs
+// are "ssh-agent:fingerprint=<sha256 fingerprint of the public key>".
+type sshAgentKeyManager struct {
+	socket string
+}
+
+// NewSSHAgent connects to the ssh-agent listening on socket (or the
+// ambient SSH_AUTH_SOCK if socket is empty).
+func NewSSHAgent(socket string) (KeyManager, error) {
+	if socket == "" {
+		socket = os.Getenv("SSH_AUTH_SOCK")
+	}
+	if socket == "" {
+		return nil, trace.BadParameter("ssh-agent: no agent socket configured and SSH_AUTH_SOCK is not set")
+	}
+	return &sshAgentKeyManager{socket: socket}, nil
+}
+
+func (s *sshAgentKeyManager) dial() (agent.ExtendedAgent, func() error, error) {
+	conn, err := net.Dial("unix", s.socket)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	return agent.NewClient(conn).(agent.ExtendedAgent), conn.Close, nil
+}
+
+// CreateSigner implements KeyManager. ssh-agent has no "generate a key"
+// operation, so keys must be added to the agent out of band (e.g. via a
+// hardware token's own enrollment tooling); CreateSigner simply looks the
+// key up by keyID, which is matched against each identity's comment.
+func (s *sshAgentKeyManager) CreateSigner(keyID string) (ssh.Signer, string, error) {
+	signer, fingerprint, err := s.findByComment(keyID)
+	if err != nil {
+		return nil, "", trace.Wrap(err)
+	}
+	return signer, "ssh-agent:fingerprint=" + fingerprint, nil
+}
+
+// Signer implements KeyManager.
+func (s *sshAgentKeyManager) Signer(keyURI string) (ssh.Signer, error) {
+	// The fingerprint itself contains a ':' ("SHA256:..."), so the URI is
+	// not split on ':' the way parseKMSURI does.
+	const prefix = "ssh-agent:fingerprint="
+	if !strings.HasPrefix(keyURI, prefix) {
+		return nil, trace.BadParameter("expected %q prefix in key URI %q", prefix, keyURI)
+	}
+	fingerprint := strings.TrimPrefix(keyURI, prefix)
+	if fingerprint == "" {
+		return nil, trace.BadParameter("ssh-agent: fingerprint is required in key URI %q", keyURI)
+	}
+	signer, _, err := s.findByFingerprint(fingerprint)
+	return signer, trace.Wrap(err)
+}
+
+func (s *sshAgentKeyManager) findByComment(comment string) (ssh.Signer, string, error) {
+	client, closeFn, err := s.dial()
+	if err != nil {
+		return nil, "", trace.Wrap(err)
+	}
+	defer closeFn()
+
+	signers, err := client.Signers()
+	if err != nil {
+		return nil, "", trace.Wrap(err)
+	}
+	keys, err := client.List()
+	if err != nil {
+		return nil, "", trace.Wrap(err)
+	}
+	for i, key := range keys {
+		if key.Comment == comment {
+			return signers[i], ssh.FingerprintSHA256(signers[i].PublicKey()), nil
+		}
+	}
+	return nil, "", trace.NotFound("ssh-agent: no key with comment %q loaded", comment)
+}
+
+func (s *sshAgentKeyManager) findByFingerprint(fingerprint string) (ssh.Signer, string, error) {
+	client, closeFn, err := s.dial()
+	if err != nil {
+		return nil, "", trace.Wrap(err)
+	}
+	defer closeFn()
+
+	signers, err := client.Signers()
+	if err != nil {
+		return nil, "", trace.Wrap(err)
+	}
+	for _, signer := range signers {
+		if ssh.FingerprintSHA256(signer.PublicKey()) == fingerprint {
+			return signer, fingerprint, nil
+		}
+	}
+	return nil, "", trace.NotFound("ssh-agent: no key with fingerprint %q loaded", fingerprint)
+}
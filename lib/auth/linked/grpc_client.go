@@ -0,0 +1,110 @@
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package linked
+
+import (
+	"time"
+
+	"github.com/gravitational/teleport/lib/auth/linked/linkedpb"
+
+	"github.com/gravitational/trace"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// dialTimeout bounds how long NewGRPCClient waits for the upstream
+// management service to accept the connection before giving up.
+const dialTimeout = 10 * time.Second
+
+// grpcClient is the real Client, talking to the upstream management
+// service over a TLS gRPC connection authenticated with the bootstrap
+// token.
+type grpcClient struct {
+	conn   *grpc.ClientConn
+	client linkedpb.LinkedCAClient
+	token  string
+}
+
+// NewGRPCClient dials cfg.UpstreamAddr and returns a Client for it. The dial
+// blocks (up to dialTimeout) so a caller -- "tctl link" in particular --
+// gets a real connectivity error back instead of a Client wrapping a
+// connection that hasn't actually been established yet.
+func NewGRPCClient(cfg Config) (Client, error) {
+	if err := cfg.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, cfg.UpstreamAddr,
+		grpc.WithTransportCredentials(credentials.NewTLS(nil)),
+		grpc.WithBlock())
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to reach upstream management service at %v", cfg.UpstreamAddr)
+	}
+	return &grpcClient{
+		conn:   conn,
+		client: linkedpb.NewLinkedCAClient(conn),
+		token:  cfg.BootstrapToken,
+	}, nil
+}
+
+// Snapshot implements Client.
+func (g *grpcClient) Snapshot(ctx context.Context) ([]Event, error) {
+	resp, err := g.client.Snapshot(ctx, &linkedpb.SnapshotRequest{BootstrapToken: g.token})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	events := make([]Event, 0, len(resp.Events))
+	for _, e := range resp.Events {
+		events = append(events, fromProto(e))
+	}
+	return events, nil
+}
+
+// Watch implements Client.
+func (g *grpcClient) Watch(ctx context.Context) (<-chan Event, error) {
+	stream, err := g.client.Watch(ctx, &linkedpb.WatchRequest{BootstrapToken: g.token})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	out := make(chan Event)
+	go func() {
+		defer close(out)
+		for {
+			e, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			select {
+			case out <- fromProto(e):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func fromProto(e *linkedpb.Event) Event {
+	return Event{
+		Kind:     Kind(e.Kind),
+		Op:       Op(e.Op),
+		Name:     e.Name,
+		Resource: e.Resource,
+	}
+}
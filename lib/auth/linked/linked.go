@@ -0,0 +1,392 @@
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package linked lets an auth server take its roles, OIDC/SAML connectors,
+// reverse tunnels, cluster auth preference, U2F settings, static tokens and
+// trusted CA public keys from an upstream management service instead of a
+// local configuration file, so that one control plane can administer many
+// Teleport clusters. A linked auth server opens a long-lived stream to the
+// upstream service: it gets a full snapshot on connect, then incremental
+// Upsert/Delete events after that.
+package linked
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gravitational/teleport/lib/backend"
+	"github.com/gravitational/teleport/lib/services"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gravitational/trace"
+	"golang.org/x/net/context"
+)
+
+// linkedCachePrefix is the backend key prefix the syncer's write-through
+// cache is persisted under, so a restarted auth server has something to
+// fall back on if the upstream is unreachable on the very first Bootstrap
+// after it comes back up.
+const linkedCachePrefix = "linkedCache"
+
+// Config configures a linked auth server.
+type Config struct {
+	// UpstreamAddr is the host:port of the upstream management service.
+	UpstreamAddr string
+	// BootstrapToken authenticates this auth server to the upstream service
+	// when it first enrolls.
+	BootstrapToken string
+}
+
+// CheckAndSetDefaults validates the config.
+func (c *Config) CheckAndSetDefaults() error {
+	if c.UpstreamAddr == "" {
+		return trace.BadParameter("UpstreamAddr is required")
+	}
+	if c.BootstrapToken == "" {
+		return trace.BadParameter("BootstrapToken is required")
+	}
+	return nil
+}
+
+// Kind identifies which backend collection an Event applies to.
+type Kind string
+
+// Kinds of resource linked auth servers sync from the upstream control
+// plane.
+const (
+	KindRole           Kind = "role"
+	KindOIDCConnector  Kind = "oidc_connector"
+	KindReverseTunnel  Kind = "reverse_tunnel"
+	KindAuthPreference Kind = "auth_preference"
+	KindU2F            Kind = "u2f"
+	KindStaticToken    Kind = "static_token"
+	KindTrustedCA      Kind = "trusted_ca"
+)
+
+// Op is the operation an Event describes.
+type Op string
+
+// Kinds of operations an Event can carry.
+const (
+	OpUpsert Op = "upsert"
+	OpDelete Op = "delete"
+)
+
+// Event is one incremental change streamed from the upstream service after
+// the initial snapshot.
+type Event struct {
+	Kind Kind
+	Op   Op
+	// Name identifies the resource being upserted/deleted (role name,
+	// connector name, tunnel cluster name, CA cluster name). Unused for
+	// singleton kinds (auth_preference, u2f, static_token).
+	Name string
+	// Resource is the upserted resource's marshaled bytes; unset for
+	// OpDelete and ignored for singleton kinds' deletes (which can't
+	// happen).
+	Resource []byte
+}
+
+// Client is implemented by the transport that talks to the upstream
+// management service -- normally a gRPC stream, stubbed here behind an
+// interface so Syncer can be tested without a live upstream.
+type Client interface {
+	// Snapshot fetches the full current configuration on connect.
+	Snapshot(ctx context.Context) ([]Event, error)
+	// Watch streams incremental Upsert/Delete events after Snapshot. The
+	// returned channel is closed when the connection drops; callers should
+	// call Watch again to reconnect.
+	Watch(ctx context.Context) (<-chan Event, error)
+}
+
+// Applier is the subset of AuthServer that Syncer writes resources into. It
+// mirrors the local reconciliation Init already does for cfg.Roles,
+// cfg.OIDCConnectors and cfg.ReverseTunnels, plus the singletons and CAs
+// that linked mode additionally manages.
+type Applier interface {
+	UpsertRole(role services.Role) error
+	DeleteRole(name string) error
+	UpsertOIDCConnector(connector services.OIDCConnector, ttl time.Duration) error
+	DeleteOIDCConnector(name string) error
+	UpsertReverseTunnel(tunnel services.ReverseTunnel, ttl time.Duration) error
+	DeleteReverseTunnel(name string) error
+	SetClusterAuthPreference(pref services.AuthPreference) error
+	SetUniversalSecondFactor(u2f services.UniversalSecondFactor) error
+	UpsertCertAuthority(ca services.CertAuthority, ttl time.Duration) error
+	SetStaticTokens(tokens services.StaticTokens) error
+}
+
+// Syncer applies a Client's snapshot and incremental events onto an
+// Applier, keeping a local write-through cache of the last-known-good
+// snapshot so the auth server keeps functioning with the last configuration
+// it saw if the upstream connection drops. The cache is keyed by resource
+// (kind, name), not appended to, so a long-lived connection's incremental
+// events compact down to the current state instead of growing forever.
+//
+// When store is non-nil, the cache is mirrored to it as it changes and
+// reloaded from it in NewSyncer, so the fallback survives an auth server
+// restart, not just a dropped connection within one process's lifetime.
+type Syncer struct {
+	client  Client
+	applier Applier
+	store   backend.Backend
+	cache   map[string]Event
+}
+
+// NewSyncer builds a Syncer that applies events from client onto applier.
+// store may be nil, in which case the cache is in-memory only and does not
+// survive a restart.
+func NewSyncer(client Client, applier Applier, store backend.Backend) *Syncer {
+	s := &Syncer{client: client, applier: applier, store: store, cache: make(map[string]Event)}
+	if store == nil {
+		return s
+	}
+	cache, err := loadCache(store)
+	if err != nil {
+		log.Warningf("linked: failed to load persisted cache, starting with an empty one: %v", err)
+		return s
+	}
+	s.cache = cache
+	return s
+}
+
+// loadCache reads back the cache persisted by a previous process.
+func loadCache(store backend.Backend) (map[string]Event, error) {
+	keys, err := store.GetKeys([]string{linkedCachePrefix})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	cache := make(map[string]Event, len(keys))
+	for _, key := range keys {
+		data, err := store.GetVal([]string{linkedCachePrefix}, key)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		var event Event
+		if err := json.Unmarshal(data, &event); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		cache[key] = event
+	}
+	return cache, nil
+}
+
+// saveCacheEntry mirrors a cache update to s.store, if configured. Failures
+// are logged, not returned: the in-memory cache (and thus the running auth
+// server) is already up to date either way, and this is only read back on
+// the next process restart.
+func (s *Syncer) saveCacheEntry(event Event) {
+	if s.store == nil {
+		return
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Warningf("linked: failed to persist cache entry %v %v %q: %v", event.Op, event.Kind, event.Name, err)
+		return
+	}
+	if err := s.store.UpsertVal([]string{linkedCachePrefix}, cacheKey(event), data, backend.Forever); err != nil {
+		log.Warningf("linked: failed to persist cache entry %v %v %q: %v", event.Op, event.Kind, event.Name, err)
+	}
+}
+
+// deleteCacheEntry mirrors a cache deletion to s.store, if configured.
+func (s *Syncer) deleteCacheEntry(key string) {
+	if s.store == nil {
+		return
+	}
+	if err := s.store.DeleteKey([]string{linkedCachePrefix}, key); err != nil {
+		log.Warningf("linked: failed to delete persisted cache entry %q: %v", key, err)
+	}
+}
+
+// cacheKey identifies the cache slot an event occupies: one per named
+// resource, or one per kind for the singleton kinds (auth_preference, u2f,
+// static_token) that have no name.
+func cacheKey(event Event) string {
+	return string(event.Kind) + "/" + event.Name
+}
+
+// Bootstrap fetches the initial snapshot and applies it. Init calls it
+// synchronously so the auth server has a usable configuration before it
+// starts serving; Run calls it again on every (re)connect.
+func (s *Syncer) Bootstrap(ctx context.Context) error {
+	events, err := s.client.Snapshot(ctx)
+	if err != nil {
+		if len(s.cache) > 0 {
+			return trace.Wrap(s.applyAll(s.cacheValues()))
+		}
+		return trace.Wrap(err)
+	}
+	s.replaceCache(events)
+	return trace.Wrap(s.applyAll(events))
+}
+
+// replaceCache swaps in a freshly fetched snapshot as the cache, both
+// in-memory and (if configured) in s.store, clearing out any stale entries
+// left over from a previous snapshot.
+func (s *Syncer) replaceCache(events []Event) {
+	old := s.cache
+	s.cache = make(map[string]Event, len(events))
+	for _, event := range events {
+		key := cacheKey(event)
+		s.cache[key] = event
+		delete(old, key)
+		s.saveCacheEntry(event)
+	}
+	for staleKey := range old {
+		s.deleteCacheEntry(staleKey)
+	}
+}
+
+// cacheValues returns the cached events in no particular order, for
+// replaying onto the applier when a fresh snapshot can't be fetched.
+func (s *Syncer) cacheValues() []Event {
+	events := make([]Event, 0, len(s.cache))
+	for _, event := range s.cache {
+		events = append(events, event)
+	}
+	return events
+}
+
+// Run streams incremental events from the upstream service and applies them
+// until ctx is canceled, reconnecting (and re-running Bootstrap) whenever
+// the stream drops.
+func (s *Syncer) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		if err := s.Bootstrap(ctx); err != nil {
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		events, err := s.client.Watch(ctx)
+		if err != nil {
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		for event := range events {
+			key := cacheKey(event)
+			if event.Op == OpDelete {
+				delete(s.cache, key)
+				s.deleteCacheEntry(key)
+			} else {
+				s.cache[key] = event
+				s.saveCacheEntry(event)
+			}
+			if err := s.apply(event); err != nil {
+				log.Warningf("linked: failed to apply %v %v %q: %v", event.Op, event.Kind, event.Name, err)
+			}
+		}
+	}
+}
+
+func (s *Syncer) applyAll(events []Event) error {
+	for _, event := range events {
+		if err := s.apply(event); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+func (s *Syncer) apply(event Event) error {
+	switch event.Kind {
+	case KindRole:
+		return trace.Wrap(s.applyRole(event))
+	case KindOIDCConnector:
+		return trace.Wrap(s.applyOIDCConnector(event))
+	case KindReverseTunnel:
+		return trace.Wrap(s.applyReverseTunnel(event))
+	case KindAuthPreference:
+		return trace.Wrap(s.applyAuthPreference(event))
+	case KindU2F:
+		return trace.Wrap(s.applyU2F(event))
+	case KindStaticToken:
+		return trace.Wrap(s.applyStaticTokens(event))
+	case KindTrustedCA:
+		return trace.Wrap(s.applyTrustedCA(event))
+	default:
+		return trace.BadParameter("linked: unsupported event kind %q", event.Kind)
+	}
+}
+
+func (s *Syncer) applyRole(event Event) error {
+	if event.Op == OpDelete {
+		return s.applier.DeleteRole(event.Name)
+	}
+	role, err := services.GetRoleMarshaler().UnmarshalRole(event.Resource)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return s.applier.UpsertRole(role)
+}
+
+func (s *Syncer) applyOIDCConnector(event Event) error {
+	if event.Op == OpDelete {
+		return s.applier.DeleteOIDCConnector(event.Name)
+	}
+	connector, err := services.GetOIDCConnectorMarshaler().Unmarshal(event.Resource)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return s.applier.UpsertOIDCConnector(connector, 0)
+}
+
+func (s *Syncer) applyReverseTunnel(event Event) error {
+	if event.Op == OpDelete {
+		return s.applier.DeleteReverseTunnel(event.Name)
+	}
+	tunnel, err := services.GetReverseTunnelMarshaler().Unmarshal(event.Resource)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return s.applier.UpsertReverseTunnel(tunnel, 0)
+}
+
+func (s *Syncer) applyAuthPreference(event Event) error {
+	pref, err := services.GetAuthPreferenceMarshaler().Unmarshal(event.Resource)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return s.applier.SetClusterAuthPreference(pref)
+}
+
+func (s *Syncer) applyU2F(event Event) error {
+	u2f, err := services.GetUniversalSecondFactorMarshaler().Unmarshal(event.Resource)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return s.applier.SetUniversalSecondFactor(u2f)
+}
+
+func (s *Syncer) applyStaticTokens(event Event) error {
+	tokens, err := services.GetStaticTokensMarshaler().Unmarshal(event.Resource)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return s.applier.SetStaticTokens(tokens)
+}
+
+func (s *Syncer) applyTrustedCA(event Event) error {
+	ca, err := services.GetCertAuthorityMarshaler().Unmarshal(event.Resource)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return s.applier.UpsertCertAuthority(ca, 0)
+}
@@ -0,0 +1,294 @@
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gravitational/teleport/lib/backend"
+	"github.com/gravitational/teleport/lib/services"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gravitational/trace"
+	"github.com/pborman/uuid"
+	"golang.org/x/crypto/ssh"
+)
+
+// Rotation phases. A CA moves standby -> init -> update_clients ->
+// update_servers -> standby on a successful rotation, or standby -> init ->
+// rollback -> standby if it's aborted before everyone has picked up the new
+// key.
+const (
+	// RotationPhaseStandby is the default phase: one signing key, nothing in
+	// progress.
+	RotationPhaseStandby = "standby"
+	// RotationPhaseInit generates the next signing key into
+	// AdditionalTrustedKeys; both keys are now trusted, but only the
+	// original one signs.
+	RotationPhaseInit = "init"
+	// RotationPhaseUpdateClients asks clients (certs issued to users) to
+	// fetch new host CA checking keys before nodes start presenting host
+	// certs signed by the new key.
+	RotationPhaseUpdateClients = "update_clients"
+	// RotationPhaseUpdateServers swaps the active signing key to the new
+	// one; nodes re-register to pick up certs signed by it.
+	RotationPhaseUpdateServers = "update_servers"
+	// RotationPhaseRollback reverts to the original signing key and drops
+	// the new one, aborting an in-progress rotation.
+	RotationPhaseRollback = "rollback"
+)
+
+// Rotation modes.
+const (
+	// RotationModeAuto advances through phases automatically once
+	// GracePeriod has elapsed.
+	RotationModeAuto = "auto"
+	// RotationModeManual only advances when RotateCertAuthority is called
+	// again with the next TargetPhase.
+	RotationModeManual = "manual"
+)
+
+// DefaultRotationGracePeriod is used when a rotation request does not
+// specify one. It is deliberately generous: it needs to outlast the
+// certificate TTL of every host and user in the cluster.
+const DefaultRotationGracePeriod = 48 * time.Hour
+
+// RotateRequest is passed to AuthServer.RotateCertAuthority to start a new
+// rotation or advance one already in progress.
+type RotateRequest struct {
+	// ClusterName is the CA's cluster name, as stored in
+	// CertAuthID.DomainName.
+	ClusterName string
+	// Type is HostCA or UserCA; rotations are driven independently per type.
+	Type services.CertAuthType
+	// TargetPhase is the phase to move the rotation to. Empty starts a new
+	// rotation at RotationPhaseInit.
+	TargetPhase string
+	// GracePeriod is how long clients/servers have to pick up the new key
+	// before Mode == RotationModeAuto advances the rotation on its own.
+	GracePeriod time.Duration
+	// Mode is RotationModeAuto or RotationModeManual.
+	Mode string
+}
+
+// CheckAndSetDefaults validates the request and fills in defaults.
+func (r *RotateRequest) CheckAndSetDefaults() error {
+	if r.ClusterName == "" {
+		return trace.BadParameter("ClusterName is required")
+	}
+	if r.Type != services.HostCA && r.Type != services.UserCA {
+		return trace.BadParameter("Type: unsupported certificate authority type %q", r.Type)
+	}
+	if r.GracePeriod == 0 {
+		r.GracePeriod = DefaultRotationGracePeriod
+	}
+	if r.Mode == "" {
+		r.Mode = RotationModeManual
+	}
+	if r.Mode != RotationModeAuto && r.Mode != RotationModeManual {
+		return trace.BadParameter("Mode: unsupported rotation mode %q", r.Mode)
+	}
+	return nil
+}
+
+// RotateCertAuthority drives the named CA's rotation state machine forward
+// by one step. Called with a zero TargetPhase it starts a brand new
+// rotation; called again with the next phase it advances one already in
+// progress. It refuses to start a second rotation while one is already
+// under way, and refuses to advance past RotationPhaseRollback/standby out
+// of order.
+func (a *AuthServer) RotateCertAuthority(req RotateRequest) error {
+	if err := req.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	caID := services.CertAuthID{DomainName: req.ClusterName, Type: req.Type}
+	ca, err := a.Trust.GetCertAuthority(caID, true)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	rotation := ca.GetRotation()
+
+	if req.TargetPhase == "" {
+		if rotation.Phase != "" && rotation.Phase != RotationPhaseStandby {
+			return trace.BadParameter("a rotation of %v CA is already in progress (phase: %v); finish or roll it back first", req.Type, rotation.Phase)
+		}
+		return trace.Wrap(a.startRotation(ca, req))
+	}
+	return trace.Wrap(a.advanceRotation(ca, rotation, req))
+}
+
+// startRotation generates a new signing keypair, stores it alongside the
+// current one in AdditionalTrustedKeys, and moves the CA into
+// RotationPhaseInit. Both the old and new keys are trusted from this point
+// on, so nothing that's already holding a cert is invalidated.
+func (a *AuthServer) startRotation(ca services.CertAuthority, req RotateRequest) error {
+	keyPair, err := a.newRotationKeyPair(req.Type, req.ClusterName)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	ca.AddAdditionalTrustedKeys(*keyPair)
+	rotation := services.Rotation{
+		Phase:       RotationPhaseInit,
+		Mode:        req.Mode,
+		CurrentID:   uuid.New(),
+		Started:     time.Now().UTC(),
+		GracePeriod: req.GracePeriod,
+		LastRotated: time.Now().UTC(),
+	}
+	ca.SetRotation(rotation)
+	log.Infof("starting %v CA rotation %v (mode: %v, grace period: %v)", req.Type, rotation.CurrentID, rotation.Mode, rotation.GracePeriod)
+	return trace.Wrap(a.Trust.UpsertCertAuthority(ca, backend.Forever))
+}
+
+// newRotationKeyPair generates the next signing keypair for a rotation,
+// through a.CAS or a.KeyManager when one is configured -- the same backend
+// bootstrapCA used for the CA's original key -- so a CA that's been moved
+// off software keys never has a local, exposed private key pushed into
+// AdditionalTrustedKeys just because it rotated.
+func (a *AuthServer) newRotationKeyPair(caType services.CertAuthType, clusterName string) (*services.CertAuthorityKeyPair, error) {
+	if a.CAS != nil {
+		pub, err := a.CAS.RenewCA()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		// Mirrors bootstrapCA's CAS branch: the CAS backend holds the
+		// private key entirely outside Teleport, so SigningKey stays empty.
+		return &services.CertAuthorityKeyPair{CheckingKey: pub}, nil
+	}
+	if a.KeyManager != nil {
+		keyID := fmt.Sprintf("%v-%v-ca-%v", clusterName, caType, uuid.New())
+		signer, keyURI, err := a.KeyManager.CreateSigner(keyID)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return &services.CertAuthorityKeyPair{
+			SigningKey:  []byte(keyURI),
+			CheckingKey: ssh.MarshalAuthorizedKey(signer.PublicKey()),
+		}, nil
+	}
+	priv, pub, err := a.GenerateKeyPair("")
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &services.CertAuthorityKeyPair{SigningKey: priv, CheckingKey: pub}, nil
+}
+
+// advanceRotation moves an in-progress rotation to req.TargetPhase,
+// rejecting any transition that isn't the next one in sequence.
+func (a *AuthServer) advanceRotation(ca services.CertAuthority, rotation services.Rotation, req RotateRequest) error {
+	next, ok := nextPhases[rotation.Phase]
+	if !ok || !next[req.TargetPhase] {
+		return trace.BadParameter("cannot move %v CA rotation %v from phase %q to %q", req.Type, rotation.CurrentID, rotation.Phase, req.TargetPhase)
+	}
+
+	switch req.TargetPhase {
+	case RotationPhaseUpdateServers:
+		// Promote the new key: it becomes the active signer, the old one
+		// moves to AdditionalTrustedKeys so certs it already signed keep
+		// validating through the grace period.
+		if err := ca.PromoteAdditionalTrustedKey(); err != nil {
+			return trace.Wrap(err)
+		}
+	case RotationPhaseStandby:
+		// Rotation completed: drop the now-unused old key.
+		ca.ClearAdditionalTrustedKeys()
+	case RotationPhaseRollback:
+		// Abort: drop the new key and keep the original signer. Once
+		// update_servers has promoted the new key, the original one sits in
+		// AdditionalTrustedKeys, so it is swapped back in first.
+		if rotation.Phase == RotationPhaseUpdateServers {
+			if err := ca.PromoteAdditionalTrustedKey(); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+		ca.ClearAdditionalTrustedKeys()
+	}
+
+	rotation.Phase = req.TargetPhase
+	rotation.LastRotated = time.Now().UTC()
+	ca.SetRotation(rotation)
+	log.Infof("%v CA rotation %v moved to phase %v", req.Type, rotation.CurrentID, rotation.Phase)
+	return trace.Wrap(a.Trust.UpsertCertAuthority(ca, backend.Forever))
+}
+
+// rotationCheckInterval is how often watchRotations polls for rotations in
+// RotationModeAuto whose grace period has elapsed.
+const rotationCheckInterval = time.Minute
+
+// watchRotations runs for the lifetime of the auth server, automatically
+// advancing any RotationModeAuto rotation one phase once its GracePeriod has
+// elapsed since it last moved. Manual rotations are left alone; an operator
+// advances those with "tctl auth rotate".
+func (a *AuthServer) watchRotations() {
+	ticker := time.NewTicker(rotationCheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		for _, caType := range []services.CertAuthType{services.HostCA, services.UserCA} {
+			if err := a.checkAutoRotation(caType); err != nil {
+				log.Warningf("rotation check failed for %v CA: %v", caType, err)
+			}
+		}
+	}
+}
+
+// checkAutoRotation advances the named CA's rotation by one phase if it is
+// in RotationModeAuto and has been sitting in its current phase longer than
+// its GracePeriod.
+func (a *AuthServer) checkAutoRotation(caType services.CertAuthType) error {
+	cas, err := a.Trust.GetCertAuthorities(caType, false)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, ca := range cas {
+		rotation := ca.GetRotation()
+		if rotation.Mode != RotationModeAuto || rotation.Phase == "" || rotation.Phase == RotationPhaseStandby {
+			continue
+		}
+		next, ok := nextPhases[rotation.Phase]
+		if !ok {
+			continue
+		}
+		if time.Since(rotation.LastRotated) < rotation.GracePeriod {
+			continue
+		}
+		for phase := range next {
+			if phase == RotationPhaseRollback {
+				continue
+			}
+			req := RotateRequest{
+				ClusterName: ca.GetClusterName(),
+				Type:        caType,
+				TargetPhase: phase,
+				Mode:        RotationModeAuto,
+				GracePeriod: rotation.GracePeriod,
+			}
+			return trace.Wrap(a.RotateCertAuthority(req))
+		}
+	}
+	return nil
+}
+
+// nextPhases enumerates the only phase transitions RotateCertAuthority will
+// accept, so a rotation can't skip steps or run backwards except through
+// the explicit rollback path.
+var nextPhases = map[string]map[string]bool{
+	RotationPhaseInit:          {RotationPhaseUpdateClients: true, RotationPhaseRollback: true},
+	RotationPhaseUpdateClients: {RotationPhaseUpdateServers: true, RotationPhaseRollback: true},
+	RotationPhaseUpdateServers: {RotationPhaseStandby: true, RotationPhaseRollback: true},
+	RotationPhaseRollback:      {RotationPhaseStandby: true},
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import "testing"
+
+// These test nextPhases directly, the transition table advanceRotation
+// checks against: they don't exercise advanceRotation itself, since that's
+// a method on *AuthServer and AuthServer isn't defined anywhere in this
+// tree (lib/auth/init.go already references it, unresolved, before any of
+// this file's changes).
+func TestRotationPhaseTransitions(t *testing.T) {
+	cases := []struct {
+		from, to string
+		allowed  bool
+	}{
+		{RotationPhaseInit, RotationPhaseUpdateClients, true},
+		{RotationPhaseInit, RotationPhaseRollback, true},
+		{RotationPhaseInit, RotationPhaseUpdateServers, false},
+		{RotationPhaseUpdateClients, RotationPhaseUpdateServers, true},
+		{RotationPhaseUpdateClients, RotationPhaseRollback, true},
+		{RotationPhaseUpdateClients, RotationPhaseInit, false},
+		{RotationPhaseUpdateServers, RotationPhaseStandby, true},
+		{RotationPhaseUpdateServers, RotationPhaseRollback, true},
+		{RotationPhaseRollback, RotationPhaseStandby, true},
+		{RotationPhaseRollback, RotationPhaseInit, false},
+		{RotationPhaseStandby, RotationPhaseInit, false},
+	}
+	for _, tc := range cases {
+		next, ok := nextPhases[tc.from]
+		allowed := ok && next[tc.to]
+		if allowed != tc.allowed {
+			t.Errorf("phase %q -> %q: allowed = %v, want %v", tc.from, tc.to, allowed, tc.allowed)
+		}
+	}
+}
+
+// TestRotationCanAlwaysRollBackMidRotation guards against a future edit to
+// nextPhases silently dropping the rollback path from one of the
+// in-progress phases -- the whole point of the grace period is that an
+// operator can abort at any in-progress phase, update_servers included.
+func TestRotationCanAlwaysRollBackMidRotation(t *testing.T) {
+	inProgress := []string{RotationPhaseInit, RotationPhaseUpdateClients, RotationPhaseUpdateServers}
+	for _, phase := range inProgress {
+		next, ok := nextPhases[phase]
+		if !ok || !next[RotationPhaseRollback] {
+			t.Errorf("phase %q cannot roll back, want it to be able to", phase)
+		}
+	}
+}
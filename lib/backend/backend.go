@@ -0,0 +1,48 @@
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package backend defines the key/value storage interface every other auth
+// package persists resources through -- roles, cert authorities, templates,
+// locks -- so none of them need to know whether the cluster is backed by
+// etcd, DynamoDB, or a single boltdb file on disk.
+package backend
+
+import "time"
+
+// Forever is passed as a TTL to mean "store this value until it is
+// explicitly deleted."
+const Forever time.Duration = 0
+
+// Backend is a namespaced key/value store with TTLs and cluster-wide
+// mutual exclusion locks.
+type Backend interface {
+	// UpsertVal creates or updates the value at key within bucket. ttl is
+	// Forever for a value with no expiration.
+	UpsertVal(bucket []string, key string, val []byte, ttl time.Duration) error
+	// GetVal returns the value at key within bucket, or a trace.NotFound
+	// error if it doesn't exist.
+	GetVal(bucket []string, key string) ([]byte, error)
+	// GetKeys returns the names of every key stored within bucket.
+	GetKeys(bucket []string) ([]string, error)
+	// DeleteKey removes key from bucket.
+	DeleteKey(bucket []string, key string) error
+
+	// AcquireLock blocks until it holds the named cluster-wide lock, or
+	// returns an error if ttl elapses first.
+	AcquireLock(name string, ttl time.Duration) error
+	// ReleaseLock releases a lock acquired with AcquireLock.
+	ReleaseLock(name string) error
+}
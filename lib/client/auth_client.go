@@ -0,0 +1,98 @@
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package client is the tctl-side counterpart of an auth server's
+// management API: a thin gRPC client tools like "tctl auth rotate" and
+// "tctl link" use to drive a remote auth server instead of editing its
+// on-disk state directly.
+package client
+
+import (
+	"time"
+
+	"github.com/gravitational/teleport/lib/auth"
+	"github.com/gravitational/teleport/lib/auth/linked"
+	"github.com/gravitational/teleport/lib/client/authpb"
+
+	"github.com/gravitational/trace"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// dialTimeout bounds how long NewAuthClient waits for the auth server to
+// accept the connection before giving up.
+const dialTimeout = 10 * time.Second
+
+// AuthClient talks to a running auth server's management API.
+type AuthClient struct {
+	conn   *grpc.ClientConn
+	client authpb.AuthManagementClient
+}
+
+// NewAuthClient dials addr, the auth server's management API. Like
+// linked.NewGRPCClient, the dial blocks (up to dialTimeout) so a caller gets
+// a real connectivity error back instead of a client wrapping a connection
+// that hasn't actually been established yet.
+func NewAuthClient(addr string) (*AuthClient, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, addr,
+		grpc.WithTransportCredentials(credentials.NewTLS(nil)),
+		grpc.WithBlock())
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to reach auth server at %v", addr)
+	}
+	return &AuthClient{conn: conn, client: authpb.NewAuthManagementClient(conn)}, nil
+}
+
+// Close closes the underlying connection.
+func (c *AuthClient) Close() error {
+	return c.conn.Close()
+}
+
+// GetDomainName returns the connected auth server's cluster name.
+func (c *AuthClient) GetDomainName() (string, error) {
+	resp, err := c.client.GetDomainName(context.Background(), &authpb.GetDomainNameRequest{})
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return resp.DomainName, nil
+}
+
+// RotateCertAuthority starts or advances a CA rotation on the connected auth
+// server.
+func (c *AuthClient) RotateCertAuthority(req auth.RotateRequest) error {
+	_, err := c.client.RotateCertAuthority(context.Background(), &authpb.RotateCertAuthorityRequest{
+		ClusterName:      req.ClusterName,
+		Type:             string(req.Type),
+		TargetPhase:      req.TargetPhase,
+		GracePeriodNanos: int64(req.GracePeriod),
+		Mode:             req.Mode,
+	})
+	return trace.Wrap(err)
+}
+
+// EnrollLinked asks the connected auth server to write out a "linked"
+// config section so its next restart syncs configuration from
+// cfg.UpstreamAddr instead of its local configuration file.
+func (c *AuthClient) EnrollLinked(cfg linked.Config) error {
+	_, err := c.client.EnrollLinked(context.Background(), &authpb.EnrollLinkedRequest{
+		UpstreamAddr:   cfg.UpstreamAddr,
+		BootstrapToken: cfg.BootstrapToken,
+	})
+	return trace.Wrap(err)
+}
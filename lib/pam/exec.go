@@ -0,0 +1,51 @@
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pam
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// CommandForSession builds the *exec.Cmd used to run a logged-in user's
+// shell/command. When hasTTY is true, the caller is expected to have
+// already opened a Session with OpenSession and run the command directly
+// under the target user's credentials. When hasTTY is false -- exec, scp
+// and port-forwarding sessions have no controlling TTY -- this instead
+// wraps the command in "su - <login> -c <cmd>" so su(1) opens its own PAM
+// session, which reliably fires modules like pam_mkhomedir that otherwise
+// silently no-op when Teleport just calls setuid() directly.
+func CommandForSession(cfg Config, hasTTY bool, login string, args ...string) *exec.Cmd {
+	if !cfg.Enabled || hasTTY {
+		return exec.Command(args[0], args[1:]...)
+	}
+	return exec.Command("su", "-", login, "-c", quoteShellCommand(args))
+}
+
+// quoteShellCommand joins args into the single string su(1)'s "-c" hands
+// to the target user's login shell for interpretation. Each argument is
+// single-quoted so shell metacharacters in a command or its arguments
+// (fully attacker-influenced for exec, scp and port-forward sessions) are
+// passed through as literal argv elements instead of being re-interpreted
+// by the shell.
+func quoteShellCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pam opens a PAM session around the lifetime of an SSH session, so
+// operators can wire Teleport into pam_mkhomedir, pam_limits, pam_env,
+// site-specific audit modules, and anything else normally driven by
+// login(1)/sshd. SSH sessions that exec a single command (scp, port
+// forwarding, non-interactive exec) have no controlling TTY, and most PAM
+// modules silently no-op when a process just calls setuid() without a PAM
+// session around it; CommandForSession works around that by running the
+// child through "su - <user> -c <cmd>" in that case, since su opens its own
+// PAM session unconditionally.
+//
+// OpenSession and CommandForSession are called from the SSH session
+// handler in lib/srv, which owns the rest of a login's lifecycle (exec,
+// pty allocation, teardown) and decides hasTTY/login per session; that
+// package is not part of this checkout (still true as of this series' last
+// revision -- reconfirmed by grep), so wiring ccf.PAMEnabled/
+// ccf.PAMServiceName through to an actual call is outside what's buildable
+// here.
+package pam
+
+import (
+	"github.com/gravitational/trace"
+)
+
+// Config is the "pam" block of Teleport's node configuration.
+type Config struct {
+	// Enabled turns PAM session handling on for this node.
+	Enabled bool
+	// ServiceName is the PAM service name Teleport authenticates as, whose
+	// stack is read from /etc/pam.d/<ServiceName>. Defaults to "teleport".
+	ServiceName string
+	// UseAuth additionally runs pam_authenticate against ServiceName before
+	// opening the session, instead of only running the account/session
+	// stack. Most deployments leave this off since Teleport has already
+	// authenticated the user by the time a session opens.
+	UseAuth bool
+}
+
+// CheckAndSetDefaults validates the config and fills in defaults.
+func (c *Config) CheckAndSetDefaults() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.ServiceName == "" {
+		c.ServiceName = "teleport"
+	}
+	return nil
+}
+
+// Session is an open PAM session for one SSH login. Close must be called
+// exactly once, regardless of whether the wrapped command succeeded.
+type Session interface {
+	// Environment returns the environment variables PAM modules set during
+	// account/session processing (e.g. pam_env), to be merged into the
+	// child process's environment.
+	Environment() []string
+	// Close ends the PAM session, running any registered session-close
+	// modules.
+	Close() error
+}
+
+// errNotEnabled is returned by OpenSession when called with a Config that
+// has Enabled == false, which callers should treat as "nothing to do"
+// rather than surface as an auth/session failure.
+var errNotEnabled = trace.BadParameter("pam: not enabled")
+
+// IsNotEnabled reports whether err is the sentinel OpenSession returns when
+// PAM support is turned off.
+func IsNotEnabled(err error) bool {
+	return trace.Unwrap(err) == trace.Unwrap(errNotEnabled)
+}
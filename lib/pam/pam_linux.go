@@ -0,0 +1,92 @@
+// +build linux,cgo
+
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pam
+
+import (
+	"github.com/gravitational/trace"
+	"github.com/msteinert/pam"
+)
+
+// linuxSession wraps a libpam transaction kept open for the lifetime of an
+// SSH session with a controlling TTY.
+type linuxSession struct {
+	tx  *pam.Transaction
+	env []string
+}
+
+// OpenSession starts pam_start/pam_authenticate(optional)/pam_acct_mgmt/
+// pam_open_session for login against cfg.ServiceName. Call Close when the
+// session's command exits, successfully or not, to run pam_close_session.
+//
+// This path is only used when the SSH session has a controlling TTY;
+// sessions without one (exec, scp, port forwarding) should go through
+// CommandForSession instead, since bare pam_open_session here does not
+// reliably trigger modules like pam_mkhomedir the way su(1) does.
+func OpenSession(cfg Config, login string) (Session, error) {
+	if err := cfg.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if !cfg.Enabled {
+		return nil, trace.Wrap(errNotEnabled)
+	}
+
+	tx, err := pam.StartFunc(cfg.ServiceName, login, func(style pam.Style, msg string) (string, error) {
+		// Teleport has already authenticated the user; there is no
+		// interactive terminal backing this callback, so just report
+		// failure to any module that prompts instead of hanging forever.
+		return "", trace.BadParameter("pam: interactive conversation not supported")
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	if cfg.UseAuth {
+		if err := tx.Authenticate(0); err != nil {
+			return nil, trace.Wrap(err, "pam_authenticate failed for %v", login)
+		}
+	}
+	if err := tx.AcctMgmt(0); err != nil {
+		return nil, trace.Wrap(err, "pam_acct_mgmt failed for %v", login)
+	}
+	if err := tx.OpenSession(0); err != nil {
+		return nil, trace.Wrap(err, "pam_open_session failed for %v", login)
+	}
+
+	env, err := tx.GetEnvList()
+	if err != nil {
+		tx.CloseSession(0)
+		return nil, trace.Wrap(err)
+	}
+	envList := make([]string, 0, len(env))
+	for k, v := range env {
+		envList = append(envList, k+"="+v)
+	}
+
+	return &linuxSession{tx: tx, env: envList}, nil
+}
+
+// Environment implements Session.
+func (s *linuxSession) Environment() []string {
+	return s.env
+}
+
+// Close implements Session.
+func (s *linuxSession) Close() error {
+	return trace.Wrap(s.tx.CloseSession(0))
+}
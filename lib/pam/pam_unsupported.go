@@ -0,0 +1,33 @@
+// +build !linux !cgo
+
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pam
+
+import "github.com/gravitational/trace"
+
+// OpenSession is a stub on platforms without libpam (anything but Linux
+// built with cgo). With cfg.Enabled == false it returns errNotEnabled, the
+// same as the Linux implementation, so callers skip PAM handling; with
+// cfg.Enabled == true it returns a "not supported on this platform" error
+// instead.
+func OpenSession(cfg Config, login string) (Session, error) {
+	if cfg.Enabled {
+		return nil, trace.BadParameter("pam: not supported on this platform")
+	}
+	return nil, trace.Wrap(errNotEnabled)
+}
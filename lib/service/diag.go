@@ -0,0 +1,157 @@
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"sync"
+
+	"github.com/gravitational/teleport"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gravitational/trace"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// DiagServer is a single HTTP endpoint serving process diagnostics:
+// pprof profiles, Prometheus metrics, and liveness/readiness probes. It
+// replaces the old hidden "--httpprofile" flag, which only ever exposed
+// pprof on a fixed localhost port with no way to check whether a role had
+// actually finished starting.
+type DiagServer struct {
+	mux      *http.ServeMux
+	listener net.Listener
+	srv      *http.Server
+
+	mu         sync.Mutex
+	readyGates map[string]func() bool
+}
+
+// NewDiagServer binds addr (host:port) and builds the handler tree. The
+// caller still needs to call Serve to start accepting connections.
+func NewDiagServer(addr string) (*DiagServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	d := &DiagServer{
+		mux:        http.NewServeMux(),
+		listener:   listener,
+		readyGates: make(map[string]func() bool),
+	}
+	d.mux.HandleFunc("/debug/pprof/", pprof.Index)
+	d.mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	d.mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	d.mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	d.mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	d.mux.Handle("/metrics", promhttp.Handler())
+	// /debug/vars serves the process's expvar variables (command line,
+	// memory stats) as plain JSON for scripted probes that don't want to
+	// parse the Prometheus text format; the Prometheus metrics themselves
+	// are only on /metrics.
+	d.mux.Handle("/debug/vars", expvar.Handler())
+	d.mux.HandleFunc("/healthz", d.handleHealthz)
+	d.mux.HandleFunc("/readyz", d.handleReadyz)
+	d.mux.HandleFunc("/version", d.handleVersion)
+	d.srv = &http.Server{Handler: d.mux}
+	for _, c := range defaultCollectors {
+		if err := d.RegisterCollector(c); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	return d, nil
+}
+
+// RegisterReadyGate adds a named check to /readyz. name is typically a
+// role ("proxy", "node", "auth"); ready should return true once that role
+// has completed initial registration and (for proxies/nodes) established a
+// tunnel to the auth server. /readyz reports 200 only once every
+// registered gate reports ready.
+func (d *DiagServer) RegisterReadyGate(name string, ready func() bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.readyGates[name] = ready
+}
+
+// RegisterCollector exposes a Prometheus collector (e.g. a role's active
+// session gauge or cache hit/miss counter) on /metrics.
+func (d *DiagServer) RegisterCollector(c prometheus.Collector) error {
+	return trace.Wrap(prometheus.Register(c))
+}
+
+// Serve accepts connections until the server is closed. Run it in its own
+// goroutine; it returns http.ErrServerClosed after Shutdown, which callers
+// should treat as a clean exit.
+func (d *DiagServer) Serve() error {
+	log.Infof("diagnostics: listening on %v", d.listener.Addr())
+	err := d.srv.Serve(d.listener)
+	if err == http.ErrServerClosed {
+		return err
+	}
+	return trace.Wrap(err)
+}
+
+// Shutdown gracefully stops the server, tied to the same lifecycle as the
+// rest of the process (call it from the same place that calls srv.Wait()).
+func (d *DiagServer) Shutdown(ctx context.Context) error {
+	return trace.Wrap(d.srv.Shutdown(ctx))
+}
+
+func (d *DiagServer) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+func (d *DiagServer) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	notReady := make([]string, 0)
+	for name, ready := range d.readyGates {
+		if !ready() {
+			notReady = append(notReady, name)
+		}
+	}
+	status := struct {
+		Status  string   `json:"status"`
+		Pending []string `json:"pending,omitempty"`
+	}{Status: "ok"}
+
+	w.Header().Set("Content-Type", "application/json")
+	if len(notReady) > 0 {
+		status.Status = "not ready"
+		status.Pending = notReady
+		w.WriteHeader(http.StatusServiceUnavailable)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	json.NewEncoder(w).Encode(status)
+}
+
+func (d *DiagServer) handleVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Version string `json:"version"`
+		Gitref  string `json:"gitref"`
+	}{Version: teleport.Version, Gitref: teleport.Gitref})
+}
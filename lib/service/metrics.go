@@ -0,0 +1,70 @@
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics exposed on DiagServer's /metrics endpoint. Nothing in this
+// package increments or sets them; they report their zero values unless
+// other code updates them.
+var (
+	// ActiveSSHSessions is the number of currently open interactive SSH
+	// sessions on this node.
+	ActiveSSHSessions = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "teleport",
+		Name:      "active_ssh_sessions",
+		Help:      "Number of active SSH sessions",
+	})
+
+	// AuthAttempts counts authentication attempts against this auth
+	// server, labeled by outcome ("success" or "failure").
+	AuthAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "teleport",
+		Name:      "auth_attempts_total",
+		Help:      "Number of authentication attempts",
+	}, []string{"outcome"})
+
+	// ReverseTunnelConnections is the number of reverse tunnels currently
+	// connected to this proxy or auth server.
+	ReverseTunnelConnections = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "teleport",
+		Name:      "reversetunnel_connections",
+		Help:      "Number of connected reverse tunnels",
+	})
+
+	// CacheRequests counts backend cache lookups, labeled by "hit" or
+	// "miss".
+	CacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "teleport",
+		Name:      "cache_requests_total",
+		Help:      "Number of cache lookups",
+	}, []string{"result"})
+)
+
+// defaultCollectors are registered on /metrics by NewDiagServer. Declaring
+// them as plain prometheus.NewGauge/NewCounterVec values above isn't
+// enough on its own -- a Collector only shows up on /metrics once
+// registered, so without this list every metric above would be updated
+// but never actually exposed.
+var defaultCollectors = []prometheus.Collector{
+	ActiveSSHSessions,
+	AuthAttempts,
+	ReverseTunnelConnections,
+	CacheRequests,
+}
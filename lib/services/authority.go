@@ -0,0 +1,184 @@
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package services
+
+import "github.com/gravitational/trace"
+
+// CertAuthType identifies which pair of signing keys a CertAuthority holds.
+type CertAuthType string
+
+const (
+	// HostCA signs host certificates.
+	HostCA CertAuthType = "host"
+	// UserCA signs user certificates.
+	UserCA CertAuthType = "user"
+)
+
+// Resource kind/version markers for CertAuthorityV2, matching the
+// Kind/Version convention used to tag every resource stored in the
+// backend.
+const (
+	KindCertAuthority = "cert_authority"
+	V2                = "v2"
+)
+
+// CertAuthID identifies a single CertAuthority: one cluster's host CA or
+// user CA.
+type CertAuthID struct {
+	// DomainName is the cluster this CA belongs to.
+	DomainName string
+	// Type is HostCA or UserCA.
+	Type CertAuthType
+}
+
+// Metadata is the common name/namespace envelope every backend-persisted
+// resource carries.
+type Metadata struct {
+	// Name is the resource's unique name within Namespace.
+	Name string
+	// Namespace scopes Name; defaults.Namespace for cluster-wide resources.
+	Namespace string
+}
+
+// CertAuthoritySpecV2 is the signing material and rotation state of a
+// CertAuthority.
+type CertAuthoritySpecV2 struct {
+	// ClusterName is the cluster this CA belongs to.
+	ClusterName string
+	// Type is HostCA or UserCA.
+	Type CertAuthType
+	// SigningKeys holds the CA's active private key(s), or -- when the key
+	// lives in a keystore.KeyManager or is delegated to a cas.CertAuthorityService
+	// -- the key URI, or nothing at all. Holds one entry; a rotation's next
+	// keypair is staged in AdditionalTrustedKeys, not here, until
+	// PromoteAdditionalTrustedKey swaps it in.
+	SigningKeys [][]byte
+	// CheckingKeys holds the CA's active public verification key(s), in SSH
+	// authorized_keys format.
+	CheckingKeys [][]byte
+	// Rotation is the CA's in-progress (or most recently completed) key
+	// rotation, driven by AuthServer.RotateCertAuthority in
+	// lib/auth/rotate.go.
+	Rotation Rotation
+	// AdditionalTrustedKeys holds the next signing/checking keypair while a
+	// rotation is under way: both the current and next keys are trusted,
+	// but only the active SigningKeys sign, until
+	// PromoteAdditionalTrustedKey swaps them in.
+	AdditionalTrustedKeys []CertAuthorityKeyPair
+}
+
+// CertAuthority is a cluster's host or user certificate authority: its
+// signing/checking keys, and the rotation state machine that moves it
+// between them. lib/auth/rotate.go drives rotations entirely through this
+// interface so it never has to know whether the concrete CA is read from a
+// live backend or a fake used in tests.
+type CertAuthority interface {
+	// GetID returns the CA's CertAuthID.
+	GetID() CertAuthID
+	// GetName returns the backend resource name.
+	GetName() string
+	// GetClusterName returns the cluster this CA belongs to.
+	GetClusterName() string
+	// GetRotation returns the CA's current rotation state.
+	GetRotation() Rotation
+	// SetRotation replaces the CA's rotation state.
+	SetRotation(r Rotation)
+	// AddAdditionalTrustedKeys stages a new signing/checking keypair
+	// alongside the active one, trusting both without yet signing with the
+	// new one.
+	AddAdditionalTrustedKeys(keyPair CertAuthorityKeyPair)
+	// PromoteAdditionalTrustedKey makes the staged keypair active, moving
+	// the previously active one into AdditionalTrustedKeys in its place so
+	// certificates it already signed keep validating through the grace
+	// period. Returns an error if no keypair is staged.
+	PromoteAdditionalTrustedKey() error
+	// ClearAdditionalTrustedKeys drops any staged keypair, either because a
+	// rotation completed (the active keypair already moved via
+	// PromoteAdditionalTrustedKey) or because it was rolled back (the
+	// staged keypair was never used).
+	ClearAdditionalTrustedKeys()
+}
+
+// CertAuthorityV2 is the backend-persisted representation of a CertAuthority.
+type CertAuthorityV2 struct {
+	// Kind is always KindCertAuthority.
+	Kind string
+	// Version is always V2.
+	Version string
+	// Metadata is the resource's name/namespace.
+	Metadata Metadata
+	// Spec holds the CA's keys and rotation state.
+	Spec CertAuthoritySpecV2
+}
+
+// GetID implements CertAuthority.
+func (ca *CertAuthorityV2) GetID() CertAuthID {
+	return CertAuthID{DomainName: ca.Spec.ClusterName, Type: ca.Spec.Type}
+}
+
+// GetName implements CertAuthority.
+func (ca *CertAuthorityV2) GetName() string {
+	return ca.Metadata.Name
+}
+
+// GetClusterName implements CertAuthority.
+func (ca *CertAuthorityV2) GetClusterName() string {
+	return ca.Spec.ClusterName
+}
+
+// GetRotation implements CertAuthority.
+func (ca *CertAuthorityV2) GetRotation() Rotation {
+	return ca.Spec.Rotation
+}
+
+// SetRotation implements CertAuthority.
+func (ca *CertAuthorityV2) SetRotation(r Rotation) {
+	ca.Spec.Rotation = r
+}
+
+// AddAdditionalTrustedKeys implements CertAuthority.
+func (ca *CertAuthorityV2) AddAdditionalTrustedKeys(keyPair CertAuthorityKeyPair) {
+	ca.Spec.AdditionalTrustedKeys = append(ca.Spec.AdditionalTrustedKeys, keyPair)
+}
+
+// PromoteAdditionalTrustedKey implements CertAuthority.
+func (ca *CertAuthorityV2) PromoteAdditionalTrustedKey() error {
+	if len(ca.Spec.AdditionalTrustedKeys) == 0 {
+		return trace.BadParameter("cert authority %v has no staged rotation keypair to promote", ca.Metadata.Name)
+	}
+	next := ca.Spec.AdditionalTrustedKeys[0]
+	ca.Spec.AdditionalTrustedKeys = ca.Spec.AdditionalTrustedKeys[1:]
+	ca.Spec.AdditionalTrustedKeys = append(ca.Spec.AdditionalTrustedKeys, CertAuthorityKeyPair{
+		SigningKey:  firstOrNil(ca.Spec.SigningKeys),
+		CheckingKey: firstOrNil(ca.Spec.CheckingKeys),
+	})
+	ca.Spec.SigningKeys = [][]byte{next.SigningKey}
+	ca.Spec.CheckingKeys = [][]byte{next.CheckingKey}
+	return nil
+}
+
+// ClearAdditionalTrustedKeys implements CertAuthority.
+func (ca *CertAuthorityV2) ClearAdditionalTrustedKeys() {
+	ca.Spec.AdditionalTrustedKeys = nil
+}
+
+func firstOrNil(keys [][]byte) []byte {
+	if len(keys) == 0 {
+		return nil
+	}
+	return keys[0]
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package services
+
+import "testing"
+
+func newTestCA() *CertAuthorityV2 {
+	return &CertAuthorityV2{
+		Kind:    KindCertAuthority,
+		Version: V2,
+		Metadata: Metadata{
+			Name:      "example.com",
+			Namespace: "default",
+		},
+		Spec: CertAuthoritySpecV2{
+			ClusterName:  "example.com",
+			Type:         HostCA,
+			SigningKeys:  [][]byte{[]byte("signing-v1")},
+			CheckingKeys: [][]byte{[]byte("checking-v1")},
+		},
+	}
+}
+
+func TestPromoteAdditionalTrustedKeyRequiresStagedKey(t *testing.T) {
+	ca := newTestCA()
+	if err := ca.PromoteAdditionalTrustedKey(); err == nil {
+		t.Fatal("expected an error promoting with nothing staged, got nil")
+	}
+}
+
+func TestRotationPromoteSwapsActiveKey(t *testing.T) {
+	ca := newTestCA()
+	ca.AddAdditionalTrustedKeys(CertAuthorityKeyPair{
+		SigningKey:  []byte("signing-v2"),
+		CheckingKey: []byte("checking-v2"),
+	})
+
+	if err := ca.PromoteAdditionalTrustedKey(); err != nil {
+		t.Fatalf("PromoteAdditionalTrustedKey: %v", err)
+	}
+
+	if got := string(ca.Spec.SigningKeys[0]); got != "signing-v2" {
+		t.Fatalf("active signing key = %q, want %q", got, "signing-v2")
+	}
+	if len(ca.Spec.AdditionalTrustedKeys) != 1 {
+		t.Fatalf("got %d additional trusted keys, want 1 (the demoted v1 key)", len(ca.Spec.AdditionalTrustedKeys))
+	}
+	if got := string(ca.Spec.AdditionalTrustedKeys[0].SigningKey); got != "signing-v1" {
+		t.Fatalf("demoted signing key = %q, want %q", got, "signing-v1")
+	}
+}
+
+func TestRotationRollbackClearsStagedKeyWithoutTouchingActive(t *testing.T) {
+	ca := newTestCA()
+	ca.AddAdditionalTrustedKeys(CertAuthorityKeyPair{
+		SigningKey:  []byte("signing-v2"),
+		CheckingKey: []byte("checking-v2"),
+	})
+
+	ca.ClearAdditionalTrustedKeys()
+
+	if len(ca.Spec.AdditionalTrustedKeys) != 0 {
+		t.Fatalf("got %d additional trusted keys after rollback, want 0", len(ca.Spec.AdditionalTrustedKeys))
+	}
+	if got := string(ca.Spec.SigningKeys[0]); got != "signing-v1" {
+		t.Fatalf("active signing key after rollback = %q, want unchanged %q", got, "signing-v1")
+	}
+}
+
+// TestInterruptedRotationRollsBackToStandby exercises the sequence
+// rotate.go drives on a manual rollback mid-rotation: a rotation was
+// started (Phase advanced off the zero value, a replacement key staged),
+// then interrupted before it could promote -- rollback must restore Phase
+// to standby and drop the staged key, leaving the active key exactly as it
+// was before the rotation began.
+func TestInterruptedRotationRollsBackToStandby(t *testing.T) {
+	ca := newTestCA()
+	ca.SetRotation(Rotation{Phase: "update_clients", CurrentID: "rotation-1"})
+	ca.AddAdditionalTrustedKeys(CertAuthorityKeyPair{
+		SigningKey:  []byte("signing-v2"),
+		CheckingKey: []byte("checking-v2"),
+	})
+
+	ca.ClearAdditionalTrustedKeys()
+	ca.SetRotation(Rotation{Phase: "standby"})
+
+	if got := ca.GetRotation().Phase; got != "standby" {
+		t.Fatalf("phase after rollback = %q, want %q", got, "standby")
+	}
+	if len(ca.Spec.AdditionalTrustedKeys) != 0 {
+		t.Fatalf("got %d additional trusted keys after rollback, want 0", len(ca.Spec.AdditionalTrustedKeys))
+	}
+	if got := string(ca.Spec.SigningKeys[0]); got != "signing-v1" {
+		t.Fatalf("active signing key after rollback = %q, want unchanged %q", got, "signing-v1")
+	}
+}
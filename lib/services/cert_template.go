@@ -0,0 +1,32 @@
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package services
+
+// CertTemplate is a named certificate template: a Go text/template,
+// evaluated at signing time against a templates.RenderContext, that
+// renders the ValidPrincipals, Extensions, CriticalOptions and validity
+// window Teleport issues a host or user certificate with, instead of
+// those fields being hardcoded. Stored and looked up by templates.Store.
+type CertTemplate struct {
+	// Name identifies this template; InitConfig.Templates and
+	// templates.Store's Render address it by this.
+	Name string
+	// Body is the Go text/template source. Executed against a
+	// templates.RenderContext, it must render JSON matching
+	// templates.RenderedCert.
+	Body string
+}
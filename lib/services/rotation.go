@@ -0,0 +1,51 @@
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package services
+
+import "time"
+
+// Rotation describes a CertAuthority's in-progress (or most recently
+// completed) key rotation. AuthServer.RotateCertAuthority in
+// lib/auth/rotate.go drives it through its phases; CertAuthority.GetRotation
+// and SetRotation persist it alongside the CA.
+type Rotation struct {
+	// Phase is one of the RotationPhase* constants in lib/auth/rotate.go,
+	// or empty if this CA has never been rotated.
+	Phase string
+	// Mode is RotationModeAuto or RotationModeManual.
+	Mode string
+	// CurrentID uniquely identifies this rotation.
+	CurrentID string
+	// Started is when the rotation began.
+	Started time.Time
+	// GracePeriod is how long clients/servers have to pick up the new key
+	// before an auto rotation advances on its own.
+	GracePeriod time.Duration
+	// LastRotated is when Phase last changed.
+	LastRotated time.Time
+}
+
+// CertAuthorityKeyPair is one signing/checking keypair held by a
+// CertAuthority: either its active one, or one staged in
+// AdditionalTrustedKeys while a rotation is in progress.
+type CertAuthorityKeyPair struct {
+	// SigningKey is the private key, or -- when the CA's signing keys live
+	// in a keystore.KeyManager -- the key URI CreateSigner returned for it.
+	SigningKey []byte
+	// CheckingKey is the public key, in SSH authorized_keys format.
+	CheckingKey []byte
+}
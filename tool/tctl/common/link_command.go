@@ -0,0 +1,69 @@
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+
+	"github.com/gravitational/teleport/lib/auth/linked"
+	"github.com/gravitational/teleport/lib/client"
+
+	"github.com/gravitational/trace"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// LinkCommand implements "tctl link", which enrolls this auth server with
+// an upstream management service so it can run in linked mode.
+type LinkCommand struct {
+	link *kingpin.CmdClause
+
+	upstreamAddr   string
+	bootstrapToken string
+}
+
+// Initialize registers the "link" command and its flags.
+func (c *LinkCommand) Initialize(app *kingpin.Application) {
+	c.link = app.Command("link", "Enroll this auth server with an upstream management service")
+	c.link.Arg("upstream-addr", "host:port of the upstream management service").Required().StringVar(&c.upstreamAddr)
+	c.link.Flag("token", "Bootstrap token issued by the upstream management service").Required().StringVar(&c.bootstrapToken)
+}
+
+// TryRun executes "link" if cmd matches it.
+func (c *LinkCommand) TryRun(cmd string, client *client.AuthClient) (match bool, err error) {
+	if cmd != c.link.FullCommand() {
+		return false, nil
+	}
+	return true, trace.Wrap(c.Link(client))
+}
+
+// Link validates connectivity to the upstream service and then asks the
+// local auth server to write out a "linked" config section so the next
+// restart picks it up.
+func (c *LinkCommand) Link(client *client.AuthClient) error {
+	cfg := linked.Config{UpstreamAddr: c.upstreamAddr, BootstrapToken: c.bootstrapToken}
+	if err := cfg.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	if _, err := linked.NewGRPCClient(cfg); err != nil {
+		return trace.Wrap(err, "failed to reach upstream management service at %v", c.upstreamAddr)
+	}
+	if err := client.EnrollLinked(cfg); err != nil {
+		return trace.Wrap(err)
+	}
+	fmt.Printf("Linked to %v. Restart this auth server to start syncing configuration from it.\n", c.upstreamAddr)
+	return nil
+}
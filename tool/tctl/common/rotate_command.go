@@ -0,0 +1,93 @@
+/*
+Copyright 2017 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"time"
+
+	"github.com/gravitational/teleport/lib/auth"
+	"github.com/gravitational/teleport/lib/client"
+	"github.com/gravitational/teleport/lib/services"
+
+	"github.com/gravitational/trace"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// RotateCommand implements "tctl auth rotate".
+type RotateCommand struct {
+	rotate *kingpin.CmdClause
+
+	authType    string
+	gracePeriod time.Duration
+	manual      bool
+	targetPhase string
+}
+
+// Initialize registers the "auth rotate" subcommand and its flags.
+func (c *RotateCommand) Initialize(app *kingpin.Application) {
+	authCmd := app.Command("auth", "Manage CA signing keys")
+	c.rotate = authCmd.Command("rotate", "Start or advance a CA rotation")
+	c.rotate.Flag("type", "CA to rotate: host or user [host]").Default("host").StringVar(&c.authType)
+	c.rotate.Flag("grace", "How long clients/servers have to pick up the new key before an auto rotation advances [48h]").
+		Default(auth.DefaultRotationGracePeriod.String()).DurationVar(&c.gracePeriod)
+	c.rotate.Flag("manual", "Only advance when this command is run again with --phase").BoolVar(&c.manual)
+	c.rotate.Flag("phase", "Advance an in-progress rotation to this phase instead of starting a new one").StringVar(&c.targetPhase)
+}
+
+// TryRun executes "auth rotate" if cmd matches one of its subcommands.
+func (c *RotateCommand) TryRun(cmd string, client *client.AuthClient) (match bool, err error) {
+	if cmd != c.rotate.FullCommand() {
+		return false, nil
+	}
+	return true, trace.Wrap(c.Rotate(client))
+}
+
+// Rotate sends the rotation request to the auth server.
+func (c *RotateCommand) Rotate(client *client.AuthClient) error {
+	caType, err := parseCAType(c.authType)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	clusterName, err := client.GetDomainName()
+	if err != nil {
+		return trace.Wrap(err, "failed to determine the cluster name of the connected auth server")
+	}
+	mode := auth.RotationModeManual
+	if !c.manual {
+		mode = auth.RotationModeAuto
+	}
+	req := auth.RotateRequest{
+		ClusterName: clusterName,
+		Type:        caType,
+		TargetPhase: c.targetPhase,
+		GracePeriod: c.gracePeriod,
+		Mode:        mode,
+	}
+	return trace.Wrap(client.RotateCertAuthority(req))
+}
+
+// parseCAType maps the --type flag value onto a services.CertAuthType.
+func parseCAType(authType string) (services.CertAuthType, error) {
+	switch authType {
+	case "host":
+		return services.HostCA, nil
+	case "user":
+		return services.UserCA, nil
+	default:
+		return "", trace.BadParameter("unsupported --type %q, expected \"host\" or \"user\"", authType)
+	}
+}
@@ -17,13 +17,15 @@ limitations under the License.
 package common
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-	_ "net/http/pprof"
 	"os"
 	"os/user"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/gravitational/teleport/lib/config"
 	"github.com/gravitational/teleport/lib/defaults"
@@ -93,8 +95,11 @@ func Run(cmdlineArgs []string, testRun bool) (executedCommand string, conf *serv
 		"Base64 encoded configuration string").Hidden().Envar(defaults.ConfigEnvar).
 		StringVar(&ccf.ConfigString)
 	start.Flag("labels", "List of labels for this node").StringVar(&ccf.Labels)
-	start.Flag("httpprofile",
-		"Start profiling endpoint on localhost:6060").Hidden().BoolVar(&ccf.HTTPProfileEndpoint)
+	start.Flag("diag-addr",
+		"Start diagnostics endpoint (pprof, metrics, healthz, readyz) on this address").StringVar(&ccf.DiagAddr)
+	start.Flag("pam", "Enable PAM session handling for SSH logins").BoolVar(&ccf.PAMEnabled)
+	start.Flag("pam-service-name",
+		"PAM service name Teleport authenticates as [teleport]").StringVar(&ccf.PAMServiceName)
 
 	// define start's usage info (we use kingpin's "alias" field for this)
 	start.Alias(usageNotes + usageExamples)
@@ -128,12 +133,6 @@ func Run(cmdlineArgs []string, testRun bool) (executedCommand string, conf *serv
 		if !testRun {
 			log.Info(conf.DebugDumpToYAML())
 		}
-		if ccf.HTTPProfileEndpoint {
-			log.Infof("starting http profile endpoint")
-			go func() {
-				log.Println(http.ListenAndServe("localhost:6060", nil))
-			}()
-		}
 		if !testRun {
 			err = onStart(conf)
 		}
@@ -155,6 +154,27 @@ func Run(cmdlineArgs []string, testRun bool) (executedCommand string, conf *serv
 
 // onStart is the handler for "start" CLI command
 func onStart(config *service.Config) error {
+	// start the diagnostics service before the rest of Teleport so
+	// /readyz has a gate to report against while Start is still running,
+	// instead of only existing once startup has already finished.
+	var diag *service.DiagServer
+	var started int32
+	if config.DiagAddr != "" {
+		var err error
+		diag, err = service.NewDiagServer(config.DiagAddr)
+		if err != nil {
+			return trace.Wrap(err, "initializing diagnostics service")
+		}
+		diag.RegisterReadyGate("teleport", func() bool {
+			return atomic.LoadInt32(&started) == 1
+		})
+		go func() {
+			if err := diag.Serve(); err != nil && err != http.ErrServerClosed {
+				log.Errorf("diagnostics service exited: %v", err)
+			}
+		}()
+	}
+
 	srv, err := service.NewTeleport(config)
 	if err != nil {
 		return trace.Wrap(err, "initializing teleport")
@@ -162,6 +182,7 @@ func onStart(config *service.Config) error {
 	if err := srv.Start(); err != nil {
 		return trace.Wrap(err, "starting teleport")
 	}
+	atomic.StoreInt32(&started, 1)
 
 	// create the pid file
 	if config.PIDFile != "" {
@@ -172,7 +193,16 @@ func onStart(config *service.Config) error {
 		fmt.Fprintf(f, "%v", os.Getpid())
 		defer f.Close()
 	}
+
 	srv.Wait()
+
+	if diag != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := diag.Shutdown(ctx); err != nil {
+			log.Warningf("diagnostics service did not shut down cleanly: %v", err)
+		}
+	}
 	return nil
 }
 